@@ -9,6 +9,8 @@ import (
 
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-starter-template/server/events"
 )
 
 const pluginIDGoFileTemplate = `// This file is automatically generated. Do not modify it manually.
@@ -33,6 +35,14 @@ func init() {
 }
 `
 
+const clientPluginIDGoFileTemplate = `// This file is automatically generated. Do not modify it manually.
+
+package client
+
+// PluginID is the ID of the plugin the client calls.
+const PluginID = %q
+`
+
 const pluginIDJSFileTemplate = `// This file is automatically generated. Do not modify it manually.
 
 const manifest = JSON.parse(` + "`" + `
@@ -160,7 +170,8 @@ func dumpPluginVersion(manifest *model.Manifest) {
 	fmt.Printf("%s", manifest.Version)
 }
 
-// applyManifest propagates the plugin_id into the server and webapp folders, as necessary
+// applyManifest propagates the plugin_id into the server and webapp folders, as necessary, along
+// with the payload types of the WebSocket events for the webapp
 func applyManifest(manifest *model.Manifest) error {
 	if manifest.HasServer() {
 		// generate JSON representation of Manifest.
@@ -178,6 +189,15 @@ func applyManifest(manifest *model.Manifest) error {
 		); err != nil {
 			return errors.Wrap(err, "failed to write server/manifest.go")
 		}
+
+		// write the plugin id the client of the plugin API calls.
+		if err := os.WriteFile(
+			"server/client/plugin_id.go",
+			fmt.Appendf(nil, clientPluginIDGoFileTemplate, manifest.Id),
+			0o600,
+		); err != nil {
+			return errors.Wrap(err, "failed to write server/client/plugin_id.go")
+		}
 	}
 
 	if manifest.HasWebapp() {
@@ -201,6 +221,15 @@ func applyManifest(manifest *model.Manifest) error {
 		); err != nil {
 			return errors.Wrap(err, "failed to open webapp/src/manifest.ts")
 		}
+
+		// write the payload types of the WebSocket events the server publishes.
+		if err := os.WriteFile(
+			"webapp/src/websocket_events.ts",
+			[]byte(events.TypeScript()),
+			0o600,
+		); err != nil {
+			return errors.Wrap(err, "failed to write webapp/src/websocket_events.ts")
+		}
 	}
 
 	return nil
@@ -1,22 +1,142 @@
 package main
 
 import (
+	"encoding/json"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-starter-template/server/client"
+	"github.com/mattermost/mattermost-plugin-starter-template/server/command"
 )
 
-// initRouter initializes the HTTP router for the plugin.
+// initRouter initializes the HTTP router for the plugin. Every route must be documented, see
+// RouteDoc.
 func (p *Plugin) initRouter() *mux.Router {
 	router := mux.NewRouter()
+	p.routes = nil
 
-	// Middleware to require that the user is logged in
-	router.Use(p.MattermostAuthorizationRequired)
+	// Middleware to correlate and log the requests, and to recover from the panics of the
+	// handlers
+	router.Use(p.RequestLogging, p.Recovery)
 
 	apiRouter := router.PathPrefix("/api/v1").Subrouter()
 
-	apiRouter.HandleFunc("/hello", p.HelloWorld).Methods(http.MethodGet)
+	// Middleware to require that the user is logged in, and to limit the rate of their requests
+	apiRouter.Use(p.MattermostAuthorizationRequired, p.RateLimit(rateLimitGroupAPI))
+
+	handleJSON(p, apiRouter, http.MethodGet, "/hello", RouteDoc{
+		Summary: "Greet the world",
+		Tags:    []string{"hello"},
+	}, p.HelloWorld)
+	handleJSON(p, apiRouter, http.MethodGet, "/openapi.json", RouteDoc{
+		Summary: "Get the OpenAPI document describing this API",
+		Tags:    []string{"meta"},
+	}, p.OpenAPI)
+	handleJSON(p, apiRouter, http.MethodGet, "/autocomplete/{command:.+}/{argument}", RouteDoc{
+		Summary:     "Get suggestions for a slash command argument",
+		Description: "Serves the dynamic list arguments of the plugin's slash commands. The command is the slash separated path of triggers, e.g. `hello/schedule/cancel`.",
+		Tags:        []string{"commands"},
+		Query: map[string]string{
+			"user_input": "The partially typed command",
+			"team_id":    "The team the command is typed in",
+			"channel_id": "The channel the command is typed in",
+			"root_id":    "The thread the command is typed in, if any",
+		},
+	}, p.AutocompleteSuggestions)
+	p.handle(apiRouter, http.MethodPost, "/dialog", RouteDoc{
+		Summary:     "Submit an interactive dialog",
+		Description: "Called by the server when a dialog opened by a slash command is submitted or cancelled. An empty response closes the dialog.",
+		Tags:        []string{"commands"},
+		Request:     model.SubmitDialogRequest{},
+		Response:    model.SubmitDialogResponse{},
+	}, p.SubmitDialog)
+	p.handle(apiRouter, http.MethodPost, "/actions", RouteDoc{
+		Summary:     "Handle an interactive message action",
+		Description: "Called by the server when a button or menu of a post created by the plugin is used.",
+		Tags:        []string{"commands"},
+		Request:     model.PostActionIntegrationRequest{},
+		Response:    model.PostActionIntegrationResponse{},
+	}, p.HandleAction)
+
+	teamRouter := apiRouter.PathPrefix("/teams/{team_id}").Subrouter()
+	teamRouter.Use(p.RequireTeamMember)
+
+	handleJSON(p, teamRouter, http.MethodGet, "/aliases", RouteDoc{
+		Summary:     "List the aliases of a team",
+		Description: "Lists the command aliases shared by the team, by name. Only the members of the team may list them.",
+		Tags:        []string{"commands"},
+	}, p.TeamAliases)
+
+	adminRouter := apiRouter.NewRoute().Subrouter()
+	adminRouter.Use(p.RequireSystemAdmin)
+
+	handleJSON(p, adminRouter, http.MethodGet, "/health", RouteDoc{
+		Summary:     "Get the health of the plugin",
+		Description: "Reports how many panics the API handlers recovered from. Only system administrators may get it.",
+		Tags:        []string{"meta"},
+	}, p.Health)
+	p.handle(adminRouter, http.MethodGet, "/metrics", RouteDoc{
+		Summary:     "Get the metrics of the plugin",
+		Description: "Serves counters and histograms of the HTTP requests, slash commands, background jobs and KV store operations in the Prometheus text format. Only system administrators may get them.",
+		Tags:        []string{"meta"},
+	}, p.Metrics)
+
+	webhooksRouter := apiRouter.PathPrefix("/webhooks").Subrouter()
+	webhooksRouter.Use(p.RequireSystemAdmin)
+
+	handleJSON(p, webhooksRouter, http.MethodPost, "", RouteDoc{
+		Summary:     "Create an incoming webhook",
+		Description: "Creates a webhook posting the JSON payloads it receives to a channel, as the bot. The response holds the secret signing the payloads, which is not returned again. Only system administrators may manage webhooks.",
+		Tags:        []string{"webhooks"},
+	}, p.CreateWebhook)
+	handleJSON(p, webhooksRouter, http.MethodGet, "", RouteDoc{
+		Summary: "List the incoming webhooks",
+		Tags:    []string{"webhooks"},
+	}, p.ListWebhooks)
+	handleJSON(p, webhooksRouter, http.MethodDelete, "/{webhook_id}", RouteDoc{
+		Summary: "Delete an incoming webhook",
+		Tags:    []string{"webhooks"},
+	}, p.DeleteWebhook)
+
+	// Incoming webhooks are not called by users, but authenticated by the signature of their
+	// payloads.
+	p.handle(router, http.MethodPost, "/webhooks/{webhook_id}", RouteDoc{
+		Summary:     "Post the payload of an incoming webhook",
+		Description: "Posts the JSON payload to the channel of the webhook using its template. The `X-Webhook-Timestamp` header holds when the payload was sent, in seconds since the epoch, and the `X-Webhook-Signature` header `sha256=` followed by the hex encoded HMAC-SHA256 of the timestamp, a dot and the body, keyed with the secret of the webhook. Payloads sent more than 5 minutes away from the time of the server, or already received, are rejected.",
+		Tags:        []string{"webhooks"},
+		Security:    securityWebhook,
+		Request:     map[string]any{},
+	}, p.ReceiveWebhook)
+
+	// The assets of the public/ directory, for logged in users and anonymous visitors alike. The
+	// server serves the directory itself under /public, without caching headers or templates.
+	p.handle(router, http.MethodGet, "/assets/{path:.+}", RouteDoc{
+		Summary:     "Get a file of the public directory",
+		Description: "Serves the files of the public/ directory of the plugin bundle, with an `ETag` and `Cache-Control`. A file `<name>.tmpl` is executed as an HTML template with the current user and the plugin configuration, and served as `<name>`.",
+		Tags:        []string{"assets"},
+		Security:    securityPublic,
+	}, p.ServeAsset)
+
+	// Routes for the other plugins of the server, called with PluginHTTP, e.g. through the client
+	// package.
+	pluginRouter := router.PathPrefix("/plugin/v1").Subrouter()
+	pluginRouter.Use(p.PluginAuthorizationRequired, p.RateLimit(rateLimitGroupPlugin))
+
+	handleJSON(p, pluginRouter, http.MethodGet, "/hello", RouteDoc{
+		Summary:  "Greet another plugin",
+		Tags:     []string{"plugins"},
+		Security: securityPlugin,
+	}, p.HelloWorld)
+	handleJSON(p, pluginRouter, http.MethodGet, "/teams/{team_id}/aliases", RouteDoc{
+		Summary:  "List the aliases of a team for another plugin",
+		Tags:     []string{"plugins"},
+		Security: securityPlugin,
+	}, p.TeamAliases)
 
 	return router
 }
@@ -31,7 +151,7 @@ func (p *Plugin) MattermostAuthorizationRequired(next http.Handler) http.Handler
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		userID := r.Header.Get("Mattermost-User-ID")
 		if userID == "" {
-			http.Error(w, "Not authorized", http.StatusUnauthorized)
+			p.writeError(w, r, model.NewAppError("MattermostAuthorizationRequired", "api.error.not_authorized", nil, "", http.StatusUnauthorized))
 			return
 		}
 
@@ -39,9 +159,96 @@ func (p *Plugin) MattermostAuthorizationRequired(next http.Handler) http.Handler
 	})
 }
 
-func (p *Plugin) HelloWorld(w http.ResponseWriter, r *http.Request) {
-	if _, err := w.Write([]byte("Hello, world!")); err != nil {
-		p.API.LogError("Failed to write response", "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+// HelloResponse is the response of the hello endpoint.
+type HelloResponse = client.HelloResponse
+
+func (p *Plugin) HelloWorld(r *Request, _ *Empty) (*HelloResponse, error) {
+	return &HelloResponse{Message: "Hello, world!"}, nil
+}
+
+// AutocompleteSuggestions serves the suggestions for the dynamic list arguments of the plugin's
+// slash commands. The server passes the partially typed command and the invocation context as
+// query parameters.
+func (p *Plugin) AutocompleteSuggestions(r *Request, _ *Empty) ([]model.AutocompleteListItem, error) {
+	query := r.URL.Query()
+
+	args := &model.CommandArgs{
+		UserId:    r.UserID,
+		ChannelId: query.Get("channel_id"),
+		TeamId:    query.Get("team_id"),
+		RootId:    query.Get("root_id"),
+	}
+
+	command := strings.ReplaceAll(r.Var("command"), "/", " ")
+	items, err := p.commandClient.Suggest(args, command, r.Var("argument"), query.Get("user_input"))
+	if err != nil {
+		r.Logger.Error("Failed to get autocomplete suggestions", "command", r.Var("command"), "argument", r.Var("argument"), "error", err)
+		return nil, model.NewAppError("AutocompleteSuggestions", "api.error.autocomplete", nil, "", http.StatusInternalServerError)
 	}
+
+	return items, nil
+}
+
+// TeamAliases lists the command aliases of the team, by name.
+func (p *Plugin) TeamAliases(r *Request, _ *Empty) (map[string]string, error) {
+	aliases, err := p.kvstore.GetTeamAliases(r.Var("team_id"))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get team aliases")
+	}
+	return aliases, nil
+}
+
+// SubmitDialog receives the submissions of the interactive dialogs opened by slash commands and
+// routes them to the dialog's handler. An empty response closes the dialog.
+func (p *Plugin) SubmitDialog(w http.ResponseWriter, r *http.Request) {
+	var request model.SubmitDialogRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		p.writeError(w, r, model.NewAppError("SubmitDialog", "api.error.invalid_dialog_submission", nil, err.Error(), http.StatusBadRequest))
+		return
+	}
+
+	// Only trust the user ID authenticated by the server.
+	request.UserId = r.Header.Get("Mattermost-User-ID")
+
+	response, err := p.commandClient.SubmitDialog(&request)
+	if err != nil {
+		p.requestLogger(r).Error("Failed to handle dialog submission", "callback_id", request.CallbackId, "error", err)
+		p.writeError(w, r, model.NewAppError("SubmitDialog", "api.error.dialog_submission", nil, "", http.StatusInternalServerError))
+		return
+	}
+	if response == nil {
+		return
+	}
+
+	p.writeJSON(w, http.StatusOK, response)
+}
+
+// HandleAction receives the requests of the interactive message buttons and menus posted by the
+// plugin and routes them to the action's handler.
+func (p *Plugin) HandleAction(w http.ResponseWriter, r *http.Request) {
+	var request model.PostActionIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		p.writeError(w, r, model.NewAppError("HandleAction", "api.error.invalid_action_request", nil, err.Error(), http.StatusBadRequest))
+		return
+	}
+
+	// Only trust the user ID authenticated by the server.
+	request.UserId = r.Header.Get("Mattermost-User-ID")
+
+	response, err := p.commandClient.HandleAction(&request)
+	if errors.Is(err, command.ErrInvalidSignature) {
+		p.requestLogger(r).Warn("Rejected action request with an invalid signature", "user_id", request.UserId, "post_id", request.PostId)
+		p.writeError(w, r, model.NewAppError("HandleAction", "api.error.invalid_action_signature", nil, "", http.StatusForbidden))
+		return
+	}
+	if err != nil {
+		p.requestLogger(r).Error("Failed to handle action", "post_id", request.PostId, "error", err)
+		p.writeError(w, r, model.NewAppError("HandleAction", "api.error.action", nil, "", http.StatusInternalServerError))
+		return
+	}
+	if response == nil {
+		response = &model.PostActionIntegrationResponse{}
+	}
+
+	p.writeJSON(w, http.StatusOK, response)
 }
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/pkg/errors"
+)
+
+// authorizer decides whether the authenticated user may make the request. Errors are reported as
+// internal errors.
+type authorizer func(r *http.Request, userID string) (bool, error)
+
+// authorize returns a middleware letting the requests through only if the authorizer permits
+// them. Requests without an authenticated user are rejected with 401 Unauthorized, and those the
+// authorizer denies with 403 Forbidden.
+//
+// Middleware compose with the subrouters of the routes they protect, e.g.
+//
+//	teamRouter := apiRouter.PathPrefix("/teams/{team_id}").Subrouter()
+//	teamRouter.Use(p.RequireTeamMember)
+func (p *Plugin) authorize(where string, check authorizer) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID := r.Header.Get("Mattermost-User-ID")
+			if userID == "" {
+				p.writeError(w, r, model.NewAppError(where, "api.error.not_authorized", nil, "", http.StatusUnauthorized))
+				return
+			}
+
+			permitted, err := check(r, userID)
+			if err != nil {
+				// Errors of the server API may be *model.AppError too, which must not be sent as is.
+				p.requestLogger(r).Error("Failed to authorize API request", "method", r.Method, "path", r.URL.Path, "check", where, "error", err)
+				p.writeError(w, r, model.NewAppError(where, "api.error.internal", nil, "", http.StatusInternalServerError))
+				return
+			}
+			if !permitted {
+				p.requestLogger(r).Warn("Request not permitted", "method", r.Method, "path", r.URL.Path, "user_id", userID, "check", where)
+				p.writeError(w, r, model.NewAppError(where, "api.error.forbidden", nil, "", http.StatusForbidden))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSystemAdmin only lets system administrators through.
+func (p *Plugin) RequireSystemAdmin(next http.Handler) http.Handler {
+	return p.authorize("RequireSystemAdmin", func(_ *http.Request, userID string) (bool, error) {
+		return p.client.User.HasPermissionTo(userID, model.PermissionManageSystem), nil
+	})(next)
+}
+
+// RequirePermission only lets through the users having the permission, in the scope it applies
+// to: the system, the team of the {team_id} route variable or the channel of the {channel_id}
+// route variable.
+func (p *Plugin) RequirePermission(permission *model.Permission) mux.MiddlewareFunc {
+	return p.authorize("RequirePermission", func(r *http.Request, userID string) (bool, error) {
+		switch permission.Scope {
+		case model.PermissionScopeTeam:
+			teamID, err := routeVar(r, "team_id")
+			if err != nil {
+				return false, err
+			}
+			return p.client.User.HasPermissionToTeam(userID, teamID, permission), nil
+		case model.PermissionScopeChannel:
+			channelID, err := routeVar(r, "channel_id")
+			if err != nil {
+				return false, err
+			}
+			return p.client.User.HasPermissionToChannel(userID, channelID, permission), nil
+		default:
+			return p.client.User.HasPermissionTo(userID, permission), nil
+		}
+	})
+}
+
+// RequireTeamMember only lets through the members of the team of the {team_id} route variable.
+func (p *Plugin) RequireTeamMember(next http.Handler) http.Handler {
+	return p.authorize("RequireTeamMember", func(r *http.Request, userID string) (bool, error) {
+		teamID, err := routeVar(r, "team_id")
+		if err != nil {
+			return false, err
+		}
+
+		member, err := p.client.Team.GetMember(teamID, userID)
+		if errors.Is(err, pluginapi.ErrNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, errors.Wrap(err, "failed to get team member")
+		}
+
+		return member.DeleteAt == 0, nil
+	})(next)
+}
+
+// RequireChannelMember only lets through the members of the channel of the {channel_id} route
+// variable.
+func (p *Plugin) RequireChannelMember(next http.Handler) http.Handler {
+	return p.authorize("RequireChannelMember", func(r *http.Request, userID string) (bool, error) {
+		channelID, err := routeVar(r, "channel_id")
+		if err != nil {
+			return false, err
+		}
+
+		_, err = p.client.Channel.GetMember(channelID, userID)
+		if errors.Is(err, pluginapi.ErrNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, errors.Wrap(err, "failed to get channel member")
+		}
+
+		return true, nil
+	})(next)
+}
+
+// routeVar returns the route variable with the given name, failing if the route has none.
+func routeVar(r *http.Request, name string) (string, error) {
+	value := mux.Vars(r)[name]
+	if value == "" {
+		return "", errors.Errorf("route has no {%s} variable", name)
+	}
+	return value, nil
+}
+
+// PluginAuthorizationRequired only lets through the requests of the plugins allowed by the
+// configuration. The server identifies the plugins making requests with PluginHTTP by the
+// Mattermost-Plugin-ID header, which it removes from the requests of users.
+func (p *Plugin) PluginAuthorizationRequired(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pluginID := r.Header.Get("Mattermost-Plugin-ID")
+		if pluginID == "" {
+			p.writeError(w, r, model.NewAppError("PluginAuthorizationRequired", "api.error.not_authorized", nil, "", http.StatusUnauthorized))
+			return
+		}
+		if !p.getConfiguration().isPluginAllowed(pluginID) {
+			p.requestLogger(r).Warn("Request of a plugin not permitted", "method", r.Method, "path", r.URL.Path, "plugin_id", pluginID)
+			p.writeError(w, r, model.NewAppError("PluginAuthorizationRequired", "api.error.forbidden", nil, "", http.StatusForbidden))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
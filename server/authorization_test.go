@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-starter-template/server/store/kvstore"
+)
+
+func TestAuthorization(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("HasPermissionTo", "admin-id", model.PermissionManageSystem).Return(true)
+	api.On("HasPermissionTo", "user-id", model.PermissionManageSystem).Return(false)
+	api.On("HasPermissionToTeam", "admin-id", "team-id", model.PermissionManageTeam).Return(true)
+	api.On("HasPermissionToTeam", "user-id", "team-id", model.PermissionManageTeam).Return(false)
+	api.On("GetTeamMember", "team-id", "user-id").Return(&model.TeamMember{TeamId: "team-id", UserId: "user-id"}, nil)
+	api.On("GetTeamMember", "team-id", "former-id").Return(&model.TeamMember{TeamId: "team-id", UserId: "former-id", DeleteAt: 1}, nil)
+	api.On("GetTeamMember", "team-id", "admin-id").Return(nil, model.NewAppError("GetTeamMember", "app.team.get_member.missing.app_error", nil, "", http.StatusNotFound))
+	api.On("GetTeamMember", "team-id", "broken-id").Return(nil, model.NewAppError("GetTeamMember", "app.team.get_member.app_error", nil, "", http.StatusInternalServerError))
+	api.On("GetChannelMember", "channel-id", "user-id").Return(&model.ChannelMember{ChannelId: "channel-id", UserId: "user-id"}, nil)
+	api.On("GetChannelMember", "channel-id", "admin-id").Return(nil, model.NewAppError("GetChannelMember", "app.channel.get_member.missing.app_error", nil, "", http.StatusNotFound))
+	api.On("LogWarn", "Request not permitted", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	api.On("LogError", "Failed to authorize API request", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	plugin := &Plugin{client: pluginapi.NewClient(api, &plugintest.Driver{})}
+	plugin.SetAPI(api)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	router := mux.NewRouter()
+	router.Handle("/admin", plugin.RequireSystemAdmin(ok))
+	router.Handle("/teams/{team_id}", plugin.RequireTeamMember(ok))
+	router.Handle("/teams/{team_id}/settings", plugin.RequirePermission(model.PermissionManageTeam)(ok))
+	router.Handle("/channels/{channel_id}", plugin.RequireChannelMember(ok))
+	router.Handle("/members", plugin.RequireTeamMember(ok))
+
+	for name, tc := range map[string]struct {
+		path       string
+		userID     string
+		statusCode int
+		id         string
+	}{
+		"anonymous":                          {path: "/admin", statusCode: http.StatusUnauthorized, id: "api.error.not_authorized"},
+		"system admin":                       {path: "/admin", userID: "admin-id", statusCode: http.StatusOK},
+		"not a system admin":                 {path: "/admin", userID: "user-id", statusCode: http.StatusForbidden, id: "api.error.forbidden"},
+		"team member":                        {path: "/teams/team-id", userID: "user-id", statusCode: http.StatusOK},
+		"former team member":                 {path: "/teams/team-id", userID: "former-id", statusCode: http.StatusForbidden, id: "api.error.forbidden"},
+		"not a team member":                  {path: "/teams/team-id", userID: "admin-id", statusCode: http.StatusForbidden, id: "api.error.forbidden"},
+		"team member lookup failure":         {path: "/teams/team-id", userID: "broken-id", statusCode: http.StatusInternalServerError, id: "api.error.internal"},
+		"team permission":                    {path: "/teams/team-id/settings", userID: "admin-id", statusCode: http.StatusOK},
+		"missing team permission":            {path: "/teams/team-id/settings", userID: "user-id", statusCode: http.StatusForbidden, id: "api.error.forbidden"},
+		"channel member":                     {path: "/channels/channel-id", userID: "user-id", statusCode: http.StatusOK},
+		"not a channel member":               {path: "/channels/channel-id", userID: "admin-id", statusCode: http.StatusForbidden, id: "api.error.forbidden"},
+		"route without the team_id variable": {path: "/members", userID: "user-id", statusCode: http.StatusInternalServerError, id: "api.error.internal"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.userID != "" {
+				r.Header.Set("Mattermost-User-ID", tc.userID)
+			}
+			router.ServeHTTP(w, r)
+
+			require.Equal(t, tc.statusCode, w.Code)
+			if tc.id == "" {
+				return
+			}
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			var appErr model.AppError
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&appErr))
+			assert.Equal(t, tc.id, appErr.Id)
+			assert.Equal(t, tc.statusCode, appErr.StatusCode)
+		})
+	}
+}
+
+func TestTeamAliases(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("GetTeamMember", "team-id", "user-id").Return(&model.TeamMember{TeamId: "team-id", UserId: "user-id"}, nil)
+	api.On("GetTeamMember", "team-id", "stranger-id").Return(nil, model.NewAppError("GetTeamMember", "app.team.get_member.missing.app_error", nil, "", http.StatusNotFound))
+	api.On("KVGet", "team_aliases-team-id").Return([]byte(`{"hi":"hello greet"}`), nil)
+	api.On("LogWarn", "Request not permitted", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, "request_id", mock.Anything).Return()
+	client := pluginapi.NewClient(api, &plugintest.Driver{})
+	plugin := &Plugin{client: client, kvstore: kvstore.NewKVStore(client)}
+	mockRequestLogging(api)
+	plugin.SetAPI(api)
+	plugin.router = plugin.initRouter()
+
+	serve := func(userID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/teams/team-id/aliases", nil)
+		r.Header.Set("Mattermost-User-ID", userID)
+		plugin.ServeHTTP(nil, w, r)
+		return w
+	}
+
+	t.Run("member", func(t *testing.T) {
+		w := serve("user-id")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"hi":"hello greet"}`, w.Body.String())
+	})
+
+	t.Run("not a member", func(t *testing.T) {
+		w := serve("stranger-id")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		api.AssertNumberOfCalls(t, "KVGet", 1)
+	})
+}
+
+func TestPluginAuthorizationRequired(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("LogWarn", "Request of a plugin not permitted", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, "request_id", mock.Anything).Return()
+	plugin := &Plugin{configuration: &configuration{AllowedPlugins: "com.example.other, com.example.allowed"}}
+	mockRequestLogging(api)
+	plugin.SetAPI(api)
+	plugin.router = plugin.initRouter()
+
+	for name, tc := range map[string]struct {
+		pluginID   string
+		userID     string
+		statusCode int
+	}{
+		"allowed plugin":     {pluginID: "com.example.allowed", statusCode: http.StatusOK},
+		"plugin not allowed": {pluginID: "com.example.denied", statusCode: http.StatusForbidden},
+		"user":               {userID: "user-id", statusCode: http.StatusUnauthorized},
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/plugin/v1/hello", nil)
+			if tc.pluginID != "" {
+				r.Header.Set("Mattermost-Plugin-ID", tc.pluginID)
+			}
+			if tc.userID != "" {
+				r.Header.Set("Mattermost-User-ID", tc.userID)
+			}
+			plugin.ServeHTTP(nil, w, r)
+
+			assert.Equal(t, tc.statusCode, w.Code)
+		})
+	}
+
+	t.Run("plugins cannot call the user API", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/hello", nil)
+		r.Header.Set("Mattermost-Plugin-ID", "com.example.allowed")
+		plugin.ServeHTTP(nil, w, r)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
@@ -0,0 +1,100 @@
+// Package client calls the plugin API of the starter template from other plugins of the same
+// server, through PluginHTTP. The calling plugin must be listed in the Allowed Plugins setting.
+//
+//	c := client.NewClient(p.API)
+//	aliases, err := c.TeamAliases(teamID)
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+// pluginAPIPath is the path of the plugin API, relative to the plugin.
+const pluginAPIPath = "/plugin/v1"
+
+// PluginAPI is the part of the plugin API the client uses, implemented by plugin.API.
+type PluginAPI interface {
+	PluginHTTP(request *http.Request) *http.Response
+}
+
+// Client calls the plugin API of the starter template.
+type Client struct {
+	api PluginAPI
+}
+
+// NewClient returns a client making its requests with the API of the calling plugin.
+func NewClient(api PluginAPI) *Client {
+	return &Client{api: api}
+}
+
+// HelloResponse is the response of the hello endpoint.
+type HelloResponse struct {
+	Message string `json:"message"`
+}
+
+// Hello greets the calling plugin.
+func (c *Client) Hello() (*HelloResponse, error) {
+	var response HelloResponse
+	if err := c.get("/hello", &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// TeamAliases returns the command aliases shared by the members of the team, by name.
+func (c *Client) TeamAliases(teamID string) (map[string]string, error) {
+	aliases := map[string]string{}
+	if err := c.get("/teams/"+url.PathEscape(teamID)+"/aliases", &aliases); err != nil {
+		return nil, err
+	}
+	return aliases, nil
+}
+
+// get requests the path of the plugin API, decoding the JSON response into v. Error responses
+// are returned as a *model.AppError.
+func (c *Client) get(path string, v any) error {
+	request, err := http.NewRequest(http.MethodGet, "/"+PluginID+pluginAPIPath+path, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response := c.api.PluginHTTP(request)
+	if response == nil {
+		return errors.Errorf("failed to request %s", path)
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	if response.StatusCode >= http.StatusBadRequest {
+		return decodeError(response)
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(v); err != nil {
+		return errors.Wrapf(err, "failed to decode response of %s", path)
+	}
+	return nil
+}
+
+// decodeError decodes the *model.AppError of an error response, or describes the response if it
+// is not one, e.g. if the plugin is not active.
+func decodeError(response *http.Response) error {
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return errors.Wrap(err, "failed to read error response")
+	}
+
+	var appErr model.AppError
+	if err := json.Unmarshal(data, &appErr); err != nil || appErr.Id == "" {
+		return errors.Errorf("request failed with status %d: %s", response.StatusCode, data)
+	}
+	if appErr.StatusCode == 0 {
+		appErr.StatusCode = response.StatusCode
+	}
+	return &appErr
+}
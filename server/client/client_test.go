@@ -0,0 +1,62 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pluginAPIFunc serves the requests made with PluginHTTP.
+type pluginAPIFunc func(w http.ResponseWriter, r *http.Request)
+
+func (f pluginAPIFunc) PluginHTTP(request *http.Request) *http.Response {
+	w := httptest.NewRecorder()
+	f(w, request)
+	return w.Result()
+}
+
+func TestClient(t *testing.T) {
+	client := NewClient(pluginAPIFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/com.mattermost.plugin-starter-template/plugin/v1/hello":
+			_, _ = w.Write([]byte(`{"message":"Hello, world!"}`))
+		case "/com.mattermost.plugin-starter-template/plugin/v1/teams/team-id/aliases":
+			_, _ = w.Write([]byte(`{"hi":"hello greet"}`))
+		case "/com.mattermost.plugin-starter-template/plugin/v1/teams/forbidden-id/aliases":
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(model.NewAppError("PluginAuthorizationRequired", "api.error.forbidden", nil, "", http.StatusForbidden).ToJSON()))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	t.Run("hello", func(t *testing.T) {
+		response, err := client.Hello()
+		require.NoError(t, err)
+		assert.Equal(t, "Hello, world!", response.Message)
+	})
+
+	t.Run("team aliases", func(t *testing.T) {
+		aliases, err := client.TeamAliases("team-id")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"hi": "hello greet"}, aliases)
+	})
+
+	t.Run("error response", func(t *testing.T) {
+		_, err := client.TeamAliases("forbidden-id")
+		var appErr *model.AppError
+		require.ErrorAs(t, err, &appErr)
+		assert.Equal(t, "api.error.forbidden", appErr.Id)
+		assert.Equal(t, http.StatusForbidden, appErr.StatusCode)
+	})
+
+	t.Run("response of another kind", func(t *testing.T) {
+		_, err := client.TeamAliases("unknown-id")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "status 404")
+	})
+}
@@ -0,0 +1,228 @@
+package command
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+// actionPath is the plugin-relative path receiving interactive message action requests.
+const actionPath = "api/v1/actions"
+
+const (
+	// actionIDContextKey holds the ID of the Action handling the request.
+	actionIDContextKey = "action_id"
+	// postIDContextKey holds the ID of the post the action was created for.
+	postIDContextKey = "post_id"
+	// signatureContextKey holds the signature of the rest of the context.
+	signatureContextKey = "signature"
+	// selectedOptionContextKey is added by the server to the context of select menus.
+	selectedOptionContextKey = "selected_option"
+)
+
+// ErrInvalidSignature is returned when the context of an action request was not signed by the
+// plugin, e.g. because a user crafted a post with a button pointing at the plugin.
+var ErrInvalidSignature = errors.New("invalid action signature")
+
+// ActionRequest is a click on an interactive message button, or a selection in a menu.
+type ActionRequest struct {
+	// Request is the request as sent by the server. The user ID is the authenticated user.
+	Request *model.PostActionIntegrationRequest
+
+	// Context is the context the action was created with, once its signature is verified.
+	Context map[string]any
+
+	// SelectedOption is the value selected in a menu.
+	SelectedOption string
+}
+
+// Action handles the requests of the interactive message buttons and menus created for it with
+// newButton and newSelect, typically in the attachments of a post created with
+// createPostWithActions. See the wave command for an example.
+type Action struct {
+	// ID identifies the action. It must be unique across all definitions.
+	ID string
+
+	// Handle handles a request. The response may update the original post, or reply with an
+	// ephemeral message. A nil response leaves the post as is.
+	Handle func(request *ActionRequest) (*model.PostActionIntegrationResponse, error)
+}
+
+// createPostWithActions creates the post, then adds the attachments built for it. As the actions
+// of the attachments are signed for the ID of the post, they are only built once it is known.
+func (c *Handler) createPostWithActions(post *model.Post, attachments func(postID string) ([]*model.SlackAttachment, error)) error {
+	if err := c.client.Post.CreatePost(post); err != nil {
+		return errors.Wrap(err, "failed to create post")
+	}
+
+	built, err := attachments(post.Id)
+	if err != nil {
+		return err
+	}
+	model.ParseSlackAttachment(post, built)
+
+	if err := c.client.Post.UpdatePost(post); err != nil {
+		return errors.Wrap(err, "failed to add the actions to the post")
+	}
+	return nil
+}
+
+// updatePost returns a response updating the original post of the request, once changed by the
+// update function, e.g. to replace its message or remove its attachments.
+func (c *Handler) updatePost(request *ActionRequest, update func(post *model.Post)) (*model.PostActionIntegrationResponse, error) {
+	post, err := c.client.Post.GetPost(request.Request.PostId)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get original post")
+	}
+
+	update(post)
+
+	return &model.PostActionIntegrationResponse{Update: post}, nil
+}
+
+// newButton creates an interactive message button of the post, routed to the action with the
+// given ID. The context is signed, and passed back to the action once verified.
+func (c *Handler) newButton(postID, actionID, name, style string, context map[string]any) (*model.PostAction, error) {
+	integration, err := c.newActionIntegration(postID, actionID, context)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.PostAction{
+		Type:        model.PostActionTypeButton,
+		Name:        name,
+		Style:       style,
+		Integration: integration,
+	}, nil
+}
+
+// newSelect creates an interactive message menu of the post, routed to the action with the given
+// ID. The context is signed, and passed back to the action once verified along with the
+// selection.
+func (c *Handler) newSelect(postID, actionID, name string, options []*model.PostActionOptions, context map[string]any) (*model.PostAction, error) {
+	integration, err := c.newActionIntegration(postID, actionID, context)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.PostAction{
+		Type:        model.PostActionTypeSelect,
+		Name:        name,
+		Options:     options,
+		Integration: integration,
+	}, nil
+}
+
+// newActionIntegration builds the integration pointing at the plugin, with the context signed
+// along with the ID of the post, so that the signature cannot be reused on another post.
+func (c *Handler) newActionIntegration(postID, actionID string, context map[string]any) (*model.PostActionIntegration, error) {
+	if _, ok := c.actions[actionID]; !ok {
+		return nil, errors.Errorf("unknown action %q", actionID)
+	}
+	if postID == "" {
+		return nil, errors.Errorf("missing post ID of action %q", actionID)
+	}
+
+	signed := maps.Clone(context)
+	if signed == nil {
+		signed = map[string]any{}
+	}
+	signed[actionIDContextKey] = actionID
+	signed[postIDContextKey] = postID
+
+	signature, err := c.signActionContext(signed)
+	if err != nil {
+		return nil, err
+	}
+	signed[signatureContextKey] = signature
+
+	return &model.PostActionIntegration{
+		URL:     fmt.Sprintf("/plugins/%s/%s", c.pluginID, actionPath),
+		Context: signed,
+	}, nil
+}
+
+// HandleAction verifies the signature of an interactive message action request, and that it
+// comes from the post the action was created for, and routes it to its action.
+func (c *Handler) HandleAction(request *model.PostActionIntegrationRequest) (*model.PostActionIntegrationResponse, error) {
+	context := maps.Clone(request.Context)
+	signature, _ := context[signatureContextKey].(string)
+	selectedOption, _ := context[selectedOptionContextKey].(string)
+	delete(context, signatureContextKey)
+	delete(context, selectedOptionContextKey)
+
+	expected, err := c.signActionContext(context)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return nil, ErrInvalidSignature
+	}
+	if postID, _ := context[postIDContextKey].(string); postID != request.PostId {
+		return nil, ErrInvalidSignature
+	}
+	delete(context, postIDContextKey)
+
+	actionID, _ := context[actionIDContextKey].(string)
+	action, ok := c.actions[actionID]
+	if !ok {
+		return nil, errors.Errorf("unknown action %q", actionID)
+	}
+	delete(context, actionIDContextKey)
+
+	return action.Handle(&ActionRequest{
+		Request:        request,
+		Context:        context,
+		SelectedOption: selectedOption,
+	})
+}
+
+// signActionContext computes the signature of the context with the plugin's signing key. The
+// context is serialized as JSON, which orders map keys.
+func (c *Handler) signActionContext(context map[string]any) (string, error) {
+	key, err := c.actionSigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(context)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal action context")
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// actionSigningKey returns the key signing action contexts, loading it on first use.
+func (c *Handler) actionSigningKey() ([]byte, error) {
+	c.signingKeyLock.Lock()
+	defer c.signingKeyLock.Unlock()
+
+	if c.signingKey == nil {
+		key, err := c.kvstore.GetActionSigningKey()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get action signing key")
+		}
+		c.signingKey = key
+	}
+
+	return c.signingKey, nil
+}
+
+// indexActions collects the actions of the definitions and their subcommands by ID.
+func indexActions(actions map[string]*Action, definitions []*Definition) {
+	for _, definition := range definitions {
+		for _, action := range definition.Actions {
+			actions[action.ID] = action
+		}
+		indexActions(actions, definition.Subcommands)
+	}
+}
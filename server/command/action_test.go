@@ -0,0 +1,118 @@
+package command
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// actionRequest simulates the request sent by the server when the action is triggered, with the
+// context round tripping through JSON as it does through the post's props.
+func actionRequest(t *testing.T, action *model.PostAction, selectedOption string) *model.PostActionIntegrationRequest {
+	t.Helper()
+
+	b, err := json.Marshal(action.Integration.Context)
+	require.NoError(t, err)
+
+	var context map[string]any
+	require.NoError(t, json.Unmarshal(b, &context))
+	if selectedOption != "" {
+		context[selectedOptionContextKey] = selectedOption
+	}
+
+	return &model.PostActionIntegrationRequest{
+		UserId:  "user-id",
+		PostId:  "post-id",
+		Context: context,
+	}
+}
+
+func TestActions(t *testing.T) {
+	env := setupTest()
+
+	var handled *ActionRequest
+	definition := &Definition{
+		Trigger: "hello",
+		Actions: []*Action{
+			{
+				ID: "wave",
+				Handle: func(request *ActionRequest) (*model.PostActionIntegrationResponse, error) {
+					handled = request
+					return &model.PostActionIntegrationResponse{Update: &model.Post{Message: "Waved back!"}}, nil
+				},
+			},
+		},
+	}
+
+	handler := &Handler{client: env.client, pluginID: "plugin-id", actions: map[string]*Action{}, signingKey: []byte("signing-key")}
+	indexActions(handler.actions, []*Definition{definition})
+
+	t.Run("button", func(t *testing.T) {
+		button, err := handler.newButton("post-id", "wave", "Wave", "primary", map[string]any{"count": 2, "user": "alice"})
+		require.NoError(t, err)
+		assert.Equal(t, "/plugins/plugin-id/api/v1/actions", button.Integration.URL)
+
+		response, err := handler.HandleAction(actionRequest(t, button, ""))
+		require.NoError(t, err)
+		assert.Equal(t, "Waved back!", response.Update.Message)
+		assert.Equal(t, map[string]any{"count": float64(2), "user": "alice"}, handled.Context)
+		assert.Equal(t, "post-id", handled.Request.PostId)
+	})
+
+	t.Run("select", func(t *testing.T) {
+		menu, err := handler.newSelect("post-id", "wave", "Wave", []*model.PostActionOptions{{Text: "Left", Value: "left"}}, nil)
+		require.NoError(t, err)
+
+		_, err = handler.HandleAction(actionRequest(t, menu, "left"))
+		require.NoError(t, err)
+		assert.Equal(t, "left", handled.SelectedOption)
+		assert.Empty(t, handled.Context)
+	})
+
+	t.Run("tampered context", func(t *testing.T) {
+		button, err := handler.newButton("post-id", "wave", "Wave", "", map[string]any{"user": "alice"})
+		require.NoError(t, err)
+
+		request := actionRequest(t, button, "")
+		request.Context["user"] = "mallory"
+		_, err = handler.HandleAction(request)
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+
+		delete(request.Context, signatureContextKey)
+		_, err = handler.HandleAction(request)
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("context copied to another post", func(t *testing.T) {
+		button, err := handler.newButton("post-id", "wave", "Wave", "", nil)
+		require.NoError(t, err)
+
+		request := actionRequest(t, button, "")
+		request.PostId = "other-post-id"
+		_, err = handler.HandleAction(request)
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("unknown action", func(t *testing.T) {
+		_, err := handler.newButton("post-id", "unknown", "Wave", "", nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("missing post", func(t *testing.T) {
+		_, err := handler.newButton("", "wave", "Wave", "", nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("update post", func(t *testing.T) {
+		env.api.On("GetPost", "post-id").Return(&model.Post{Id: "post-id", Message: "Hello"}, nil)
+
+		response, err := handler.updatePost(&ActionRequest{Request: &model.PostActionIntegrationRequest{PostId: "post-id"}}, func(post *model.Post) {
+			post.Message += " (answered)"
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "Hello (answered)", response.Update.Message)
+	})
+}
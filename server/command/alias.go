@@ -0,0 +1,351 @@
+package command
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/shared/i18n"
+
+	"github.com/mattermost/mattermost-plugin-starter-template/server/events"
+)
+
+// aliasNamePattern matches valid alias names. Names cannot start with @, ~ or -, so that
+// greetings and flags are never looked up as aliases.
+var aliasNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// aliasPlaceholderPattern matches the placeholders of alias expansions: $1 to $9 for a single
+// argument of the alias, and $@ for all of them.
+var aliasPlaceholderPattern = regexp.MustCompile(`\$([1-9]|@)`)
+
+// aliasArguments returns the arguments of the alias subcommands: the name of the alias, and its
+// expansion when adding it.
+func aliasArguments(withExpansion bool) []*Argument {
+	arguments := []*Argument{
+		{Name: "name", HelpText: "The name of the alias", Hint: "name", Required: true},
+	}
+	if withExpansion {
+		arguments = append(arguments, &Argument{
+			Name:     "expansion",
+			HelpText: "What the alias stands for, after -- if it has flags. $1 to $9 and $@ are replaced by the arguments of the alias",
+			Hint:     "expansion",
+			Required: true,
+			Variadic: true,
+		})
+	}
+	return arguments
+}
+
+// expandAliases wraps the dispatch of commands, replacing an alias following the trigger with
+// its expansion, e.g. `/hello standup` with `/hello @alice @bob ~standup`. The aliases of the
+// user take precedence over those of the team, and subcommands over both.
+func (c *Handler) expandAliases(next HandleFunc) HandleFunc {
+	return func(args *model.CommandArgs) (*model.CommandResponse, error) {
+		fields, err := tokenize(args.Command)
+		if err != nil || len(fields) < 2 || !aliasNamePattern.MatchString(strings.ToLower(fields[1])) {
+			return next(args)
+		}
+		definition := find(c.activeDefinitions(), []string{strings.TrimPrefix(fields[0], "/")})
+		if definition == nil || definition.subcommand(fields[1]) != nil {
+			return next(args)
+		}
+
+		aliases, err := c.aliases(args)
+		if err != nil {
+			return nil, err
+		}
+
+		expanded, problem := expandAlias(translate(args), aliases, fields[1:])
+		if problem != "" {
+			return &model.CommandResponse{
+				ResponseType: model.CommandResponseTypeEphemeral,
+				Text:         problem,
+			}, nil
+		}
+		if expanded == nil {
+			return next(args)
+		}
+
+		expandedArgs := *args
+		expandedArgs.Command = quoteFields(append([]string{fields[0]}, expanded...))
+		return next(&expandedArgs)
+	}
+}
+
+// aliases returns the aliases available to the user in the team the command was run in.
+func (c *Handler) aliases(args *model.CommandArgs) (map[string]string, error) {
+	aliases := map[string]string{}
+	if args.TeamId != "" {
+		teamAliases, err := c.kvstore.GetTeamAliases(args.TeamId)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(aliases, teamAliases)
+	}
+
+	userAliases, err := c.kvstore.GetUserAliases(args.UserId)
+	if err != nil {
+		return nil, err
+	}
+	maps.Copy(aliases, userAliases)
+
+	return aliases, nil
+}
+
+// expandAlias replaces the alias named by the first field with its expansion, for as long as the
+// expansion starts with another alias. It returns nil if the first field is not an alias, and a
+// message for the user if the aliases cannot be expanded.
+func expandAlias(T i18n.TranslateFunc, aliases map[string]string, fields []string) ([]string, string) {
+	var chain []string
+	for len(fields) > 0 {
+		name := strings.ToLower(fields[0])
+		expansion, ok := aliases[name]
+		if !ok {
+			break
+		}
+		if slices.Contains(chain, name) {
+			return nil, T("command.alias.self_reference", map[string]any{"Name": chain[0], "Chain": formatAliasChain(append(chain, name))})
+		}
+		chain = append(chain, name)
+
+		var problem string
+		fields, problem = substituteArguments(T, name, expansion, fields[1:])
+		if problem != "" {
+			return nil, problem
+		}
+	}
+
+	if len(chain) == 0 {
+		return nil, ""
+	}
+	return fields, ""
+}
+
+// substituteArguments fills the placeholders of the expansion with the arguments the alias was
+// invoked with. The arguments are appended if the expansion has no placeholders.
+func substituteArguments(T i18n.TranslateFunc, name, expansion string, arguments []string) ([]string, string) {
+	tokens, err := tokenize(expansion)
+	if err != nil {
+		return nil, T("command.alias.invalid", map[string]any{"Name": name, "Error": err.Error()})
+	}
+
+	var fields []string
+	substituted := false
+	required := 0
+	for _, token := range tokens {
+		if token == "$@" {
+			fields = append(fields, arguments...)
+			substituted = true
+			continue
+		}
+		fields = append(fields, aliasPlaceholderPattern.ReplaceAllStringFunc(token, func(placeholder string) string {
+			substituted = true
+			if placeholder == "$@" {
+				return strings.Join(arguments, " ")
+			}
+			i := int(placeholder[1] - '1')
+			if i >= len(arguments) {
+				required = max(required, i+1)
+				return ""
+			}
+			return arguments[i]
+		}))
+	}
+
+	if required > 0 {
+		return nil, T("command.alias.missing_arguments", map[string]any{"Name": name, "Count": required})
+	}
+	if !substituted {
+		fields = append(fields, arguments...)
+	}
+
+	return fields, ""
+}
+
+// aliasCycle returns the chain of aliases leading from the named alias back to an alias of the
+// chain, or nil if expanding the alias terminates.
+func aliasCycle(aliases map[string]string, name string) []string {
+	chain := []string{name}
+	for {
+		tokens, err := tokenize(aliases[name])
+		if err != nil || len(tokens) == 0 {
+			return nil
+		}
+		name = strings.ToLower(tokens[0])
+		if _, ok := aliases[name]; !ok {
+			return nil
+		}
+		cycle := slices.Contains(chain, name)
+		chain = append(chain, name)
+		if cycle {
+			return chain
+		}
+	}
+}
+
+// formatAliasChain formats a chain of aliases, e.g. `a` → `b` → `a`.
+func formatAliasChain(chain []string) string {
+	quoted := make([]string, 0, len(chain))
+	for _, name := range chain {
+		quoted = append(quoted, "`"+name+"`")
+	}
+	return strings.Join(quoted, " → ")
+}
+
+// scopedAliases returns the aliases of the user, or of the team if team is true.
+func (c *Handler) scopedAliases(args *model.CommandArgs, team bool) (map[string]string, error) {
+	if team {
+		return c.kvstore.GetTeamAliases(args.TeamId)
+	}
+	return c.kvstore.GetUserAliases(args.UserId)
+}
+
+// setScopedAlias sets an alias of the user, or of the team if team is true. An empty expansion
+// removes the alias.
+func (c *Handler) setScopedAlias(args *model.CommandArgs, team bool, name, expansion string) error {
+	if team {
+		return c.kvstore.SetTeamAlias(args.TeamId, name, expansion)
+	}
+	return c.kvstore.SetUserAlias(args.UserId, name, expansion)
+}
+
+func (c *Handler) executeAliasAddCommand(args *model.CommandArgs, values *Values) (*model.CommandResponse, error) {
+	return c.addAlias(args, values, false)
+}
+
+func (c *Handler) executeTeamAliasAddCommand(args *model.CommandArgs, values *Values) (*model.CommandResponse, error) {
+	return c.addAlias(args, values, true)
+}
+
+func (c *Handler) executeAliasRemoveCommand(args *model.CommandArgs, values *Values) (*model.CommandResponse, error) {
+	return c.removeAlias(args, values, false)
+}
+
+func (c *Handler) executeTeamAliasRemoveCommand(args *model.CommandArgs, values *Values) (*model.CommandResponse, error) {
+	return c.removeAlias(args, values, true)
+}
+
+// addAlias validates and stores the alias given by the name and expansion arguments.
+func (c *Handler) addAlias(args *model.CommandArgs, values *Values, team bool) (*model.CommandResponse, error) {
+	T := translate(args)
+	name := strings.ToLower(values.String("name"))
+	if !aliasNamePattern.MatchString(name) {
+		return &model.CommandResponse{
+			ResponseType: model.CommandResponseTypeEphemeral,
+			Text:         T("command.alias.invalid_name"),
+		}, nil
+	}
+
+	command := trigger(args)
+	if definition := find(c.activeDefinitions(), []string{command}); definition != nil && definition.subcommand(name) != nil {
+		return &model.CommandResponse{
+			ResponseType: model.CommandResponseTypeEphemeral,
+			Text:         T("command.alias.subcommand", map[string]any{"Name": name, "Trigger": command}),
+		}, nil
+	}
+
+	aliases, err := c.scopedAliases(args, team)
+	if err != nil {
+		return nil, err
+	}
+	expansion := quoteFields(values.Strings("expansion"))
+	aliases[name] = expansion
+	if cycle := aliasCycle(aliases, name); cycle != nil {
+		return &model.CommandResponse{
+			ResponseType: model.CommandResponseTypeEphemeral,
+			Text:         T("command.alias.cycle", map[string]any{"Name": name, "Chain": formatAliasChain(cycle)}),
+		}, nil
+	}
+
+	if err := c.setScopedAlias(args, team, name, expansion); err != nil {
+		return nil, err
+	}
+	if team {
+		events.TeamAliasesChanged.Publish(&c.client.Frontend, events.TeamAliasesChangedPayload{TeamID: args.TeamId, Name: name}, events.ToTeam(args.TeamId))
+	}
+
+	data := map[string]any{"Name": name, "Trigger": command, "Expansion": expansion}
+	text := T("command.alias.added", data)
+	if team {
+		text = T("command.alias.team_added", data)
+	}
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         text,
+	}, nil
+}
+
+// removeAlias removes the alias given by the name argument.
+func (c *Handler) removeAlias(args *model.CommandArgs, values *Values, team bool) (*model.CommandResponse, error) {
+	T := translate(args)
+	name := strings.ToLower(values.String("name"))
+
+	aliases, err := c.scopedAliases(args, team)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := aliases[name]; !ok {
+		return &model.CommandResponse{
+			ResponseType: model.CommandResponseTypeEphemeral,
+			Text:         T("command.alias.not_found", map[string]any{"Name": name}),
+		}, nil
+	}
+
+	if err := c.setScopedAlias(args, team, name, ""); err != nil {
+		return nil, err
+	}
+	if team {
+		events.TeamAliasesChanged.Publish(&c.client.Frontend, events.TeamAliasesChangedPayload{TeamID: args.TeamId, Name: name, Removed: true}, events.ToTeam(args.TeamId))
+	}
+
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         T("command.alias.removed", map[string]any{"Name": name}),
+	}, nil
+}
+
+func (c *Handler) executeAliasListCommand(args *model.CommandArgs, values *Values) (*model.CommandResponse, error) {
+	T := translate(args)
+	command := trigger(args)
+
+	var sb strings.Builder
+	for _, team := range []bool{false, true} {
+		if team && args.TeamId == "" {
+			continue
+		}
+		aliases, err := c.scopedAliases(args, team)
+		if err != nil {
+			return nil, err
+		}
+		if len(aliases) == 0 {
+			continue
+		}
+
+		if sb.Len() > 0 {
+			sb.WriteString("\n\n")
+		}
+		if team {
+			sb.WriteString(T("command.alias.list.team"))
+		} else {
+			sb.WriteString(T("command.alias.list.user"))
+		}
+		for _, name := range slices.Sorted(maps.Keys(aliases)) {
+			fmt.Fprintf(&sb, "\n- `/%s %s`: `/%s %s`", command, name, command, aliases[name])
+		}
+	}
+
+	if sb.Len() == 0 {
+		return &model.CommandResponse{
+			ResponseType: model.CommandResponseTypeEphemeral,
+			Text:         T("command.alias.list.empty", map[string]any{"Trigger": command}),
+		}, nil
+	}
+
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         sb.String(),
+	}, nil
+}
@@ -0,0 +1,149 @@
+package command
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-starter-template/server/store/kvstore"
+)
+
+func TestExpandAlias(t *testing.T) {
+	aliases := map[string]string{
+		"team":   "@alice @bob ~town-square",
+		"dm":     "--dm $@",
+		"pair":   "$2 $1",
+		"prefix": `"team-$1"`,
+		"both":   "team $@",
+		"loop":   "again",
+		"again":  "loop",
+	}
+
+	for name, tc := range map[string]struct {
+		fields   []string
+		expected []string
+		problem  string
+	}{
+		"not an alias":           {fields: []string{"@alice"}},
+		"arguments are appended": {fields: []string{"team", "@carol"}, expected: []string{"@alice", "@bob", "~town-square", "@carol"}},
+		"all arguments":          {fields: []string{"dm", "@alice", "@bob"}, expected: []string{"--dm", "@alice", "@bob"}},
+		"positional arguments":   {fields: []string{"pair", "a", "b"}, expected: []string{"b", "a"}},
+		"within a word":          {fields: []string{"prefix", "x"}, expected: []string{"team-x"}},
+		"case insensitive":       {fields: []string{"TEAM"}, expected: []string{"@alice", "@bob", "~town-square"}},
+		"nested aliases":         {fields: []string{"both", "@carol"}, expected: []string{"@alice", "@bob", "~town-square", "@carol"}},
+		"missing arguments":      {fields: []string{"pair", "a"}, problem: "Alias `pair` expects at least 2 arguments."},
+		"cycle":                  {fields: []string{"loop"}, problem: "Alias `loop` refers to itself: `loop` → `again` → `loop`."},
+	} {
+		t.Run(name, func(t *testing.T) {
+			expanded, problem := expandAlias(testTranslations("en"), aliases, tc.fields)
+			assert.Equal(t, tc.expected, expanded)
+			assert.Equal(t, tc.problem, problem)
+		})
+	}
+}
+
+func TestAliases(t *testing.T) {
+	setup := func() (*env, *Handler, *model.CommandArgs) {
+		env := setupTest()
+		env.api.On("KVGet", "user_aliases-sender-id").Return([]byte(`{"greet":"wave $@","hi":"@alice $@","mine":"alias list","standup":"@bob"}`), nil)
+		env.api.On("KVGet", "team_aliases-team-id").Return([]byte(`{"standup":"~standup"}`), nil)
+
+		handler := &Handler{client: env.client, kvstore: kvstore.NewKVStore(env.client)}
+		handler.definitions = []*Definition{{
+			Trigger:   "hello",
+			Arguments: []*Argument{{Name: "targets", Variadic: true}},
+			Handler: func(args *model.CommandArgs, values *Values) (*model.CommandResponse, error) {
+				return &model.CommandResponse{Text: args.Command}, nil
+			},
+			Subcommands: []*Definition{
+				{Trigger: "alias", Subcommands: []*Definition{
+					{Trigger: "add", Arguments: aliasArguments(true), Handler: handler.executeAliasAddCommand},
+					{Trigger: "list", Handler: handler.executeAliasListCommand},
+					{Trigger: "team", Subcommands: []*Definition{
+						{Trigger: "add", Arguments: aliasArguments(true), Handler: handler.executeTeamAliasAddCommand},
+						{Trigger: "remove", Arguments: aliasArguments(false), Handler: handler.executeTeamAliasRemoveCommand},
+					}},
+				}},
+			},
+		}}
+		args := &model.CommandArgs{UserId: "sender-id", TeamId: "team-id", ChannelId: "channel-id", T: testTranslations("en")}
+		return env, handler, args
+	}
+
+	handle := func(t *testing.T, handler *Handler, args *model.CommandArgs, command string) *model.CommandResponse {
+		invocation := *args
+		invocation.Command = command
+		response, err := handler.Handle(&invocation)
+		require.NoError(t, err)
+		return response
+	}
+
+	t.Run("expands aliases before dispatch", func(t *testing.T) {
+		_, handler, args := setup()
+		assert.Equal(t, "/hello @alice @carol", handle(t, handler, args, "/hello hi @carol").Text)
+		assert.Equal(t, "/hello @bob", handle(t, handler, args, "/hello standup").Text, "user aliases take precedence")
+		assert.Equal(t, "/hello @dave", handle(t, handler, args, "/hello @dave").Text)
+	})
+
+	t.Run("limits the rate of the expanded commands", func(t *testing.T) {
+		_, handler, args := setup()
+		var limited []string
+		handler.LimitRate(rateLimiterFunc(func(userID string, names ...string) (bool, time.Duration) {
+			limited = names
+			return true, 0
+		}))
+
+		assert.Equal(t, "/hello @alice @carol", handle(t, handler, args, "/hello hi @carol").Text)
+		assert.Equal(t, []string{"/hello"}, limited)
+
+		assert.Contains(t, handle(t, handler, args, "/hello mine").Text, "Your aliases:")
+		assert.Equal(t, []string{"/hello alias list", "/hello alias", "/hello"}, limited)
+	})
+
+	t.Run("adds aliases", func(t *testing.T) {
+		env, handler, args := setup()
+		env.api.On("KVSetWithOptions", "user_aliases-sender-id", mock.MatchedBy(func(data []byte) bool {
+			return string(data) == `{"greet":"wave $@","hi":"@alice $@","mine":"alias list","standup":"@bob","wave":"--dm \"good morning\""}`
+		}), mock.Anything).Return(true, nil).Once()
+
+		response := handle(t, handler, args, `/hello alias add wave -- --dm "good morning"`)
+		assert.Equal(t, "Added alias `wave`: `/hello wave` now runs `/hello --dm \"good morning\"`.", response.Text)
+		env.api.AssertNumberOfCalls(t, "KVSetWithOptions", 1)
+	})
+
+	t.Run("notifies the team of changed team aliases", func(t *testing.T) {
+		env, handler, args := setup()
+		env.api.On("KVSetWithOptions", "team_aliases-team-id", mock.Anything, mock.Anything).Return(true, nil)
+		env.api.On("PublishWebSocketEvent", "team_aliases_changed", map[string]any{"team_id": "team-id", "name": "retro", "removed": false}, &model.WebsocketBroadcast{TeamId: "team-id"}).Return().Once()
+		env.api.On("PublishWebSocketEvent", "team_aliases_changed", map[string]any{"team_id": "team-id", "name": "standup", "removed": true}, &model.WebsocketBroadcast{TeamId: "team-id"}).Return().Once()
+
+		assert.Contains(t, handle(t, handler, args, "/hello alias team add retro ~retro").Text, "Added team alias `retro`")
+		assert.Equal(t, "Removed alias `standup`.", handle(t, handler, args, "/hello alias team remove standup").Text)
+		env.api.AssertNumberOfCalls(t, "PublishWebSocketEvent", 2)
+	})
+
+	t.Run("rejects invalid aliases", func(t *testing.T) {
+		env, handler, args := setup()
+		assert.Equal(t, "`alias` is a subcommand of `/hello`, so it cannot be used as an alias.",
+			handle(t, handler, args, "/hello alias add alias @alice").Text)
+		assert.Equal(t, "Alias `wave` would refer to itself: `wave` → `greet` → `wave`.",
+			handle(t, handler, args, "/hello alias add wave greet").Text)
+		assert.Contains(t, handle(t, handler, args, "/hello alias add @x @alice").Text, "Alias names must be")
+		env.api.AssertNotCalled(t, "KVSetWithOptions", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("lists aliases", func(t *testing.T) {
+		_, handler, args := setup()
+		assert.Equal(t, "Your aliases:\n"+
+			"- `/hello greet`: `/hello wave $@`\n"+
+			"- `/hello hi`: `/hello @alice $@`\n"+
+			"- `/hello mine`: `/hello alias list`\n"+
+			"- `/hello standup`: `/hello @bob`\n\n"+
+			"Team aliases:\n"+
+			"- `/hello standup`: `/hello ~standup`", handle(t, handler, args, "/hello alias list").Text)
+	})
+}
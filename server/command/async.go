@@ -0,0 +1,113 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+// closeTimeout bounds how long Close waits for background commands to return once cancelled.
+const closeTimeout = 10 * time.Second
+
+// AsyncHandlerFunc executes a slash command in the background. The context is cancelled when the
+// plugin is deactivated.
+type AsyncHandlerFunc func(ctx context.Context, args *model.CommandArgs, values *Values) (*model.CommandResponse, error)
+
+// runAsync acknowledges the command right away and runs its handler in a tracked goroutine,
+// delivering the response to the user once it completes.
+func (c *Handler) runAsync(d *Definition, args *model.CommandArgs, values *Values, path []string) *model.CommandResponse {
+	command := "/" + joinPath(path)
+	T := translate(args)
+
+	c.background.Add(1)
+	go func() {
+		defer c.background.Done()
+
+		response, err := c.callAsync(d, args, values)
+		if err != nil {
+			c.client.Log.Warn("Background slash command failed", "command", command, "user_id", args.UserId, "error", err)
+			response = &model.CommandResponse{
+				ResponseType: model.CommandResponseTypeEphemeral,
+				Text:         T("command.async.failed", map[string]any{"Command": command, "Error": err.Error()}),
+			}
+		}
+
+		c.deliver(args, response)
+	}()
+
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         T("command.async.working", map[string]any{"Command": command}),
+	}
+}
+
+// callAsync calls the async handler, turning panics into errors since the goroutine is not
+// covered by the command middleware.
+func (c *Handler) callAsync(d *Definition, args *model.CommandArgs, values *Values) (response *model.CommandResponse, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			correlationID := model.NewId()
+			c.client.Log.Error("Recovered from a panic in a background slash command",
+				"correlation_id", correlationID,
+				"user_id", args.UserId,
+				"panic", fmt.Sprint(r),
+				"stack", string(debug.Stack()),
+			)
+			err = errors.Errorf("unexpected error, error ID `%s`", correlationID)
+		}
+	}()
+
+	return d.AsyncHandler(c.ctx, args, values)
+}
+
+// deliver posts the response of a background command: in-channel responses as a post from the
+// plugin's bot, and ephemeral responses as an ephemeral post to the invoking user.
+func (c *Handler) deliver(args *model.CommandArgs, response *model.CommandResponse) {
+	if response == nil || (response.Text == "" && len(response.Attachments) == 0) {
+		return
+	}
+
+	post := &model.Post{
+		UserId:    c.botUserID,
+		ChannelId: args.ChannelId,
+		RootId:    args.RootId,
+		Message:   response.Text,
+	}
+	if len(response.Attachments) > 0 {
+		model.ParseSlackAttachment(post, response.Attachments)
+	}
+
+	if response.ResponseType == model.CommandResponseTypeInChannel {
+		if err := c.client.Post.CreatePost(post); err != nil {
+			c.client.Log.Error("Failed to post background command result", "channel_id", args.ChannelId, "error", err)
+		}
+		return
+	}
+
+	c.client.Post.SendEphemeralPost(args.UserId, post)
+}
+
+// Close cancels the context of the background commands and waits for them to return, up to
+// closeTimeout.
+func (c *Handler) Close() error {
+	if c.cancel != nil {
+		c.cancel()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c.background.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(closeTimeout):
+		return errors.Errorf("timed out waiting for background commands after %s", closeTimeout)
+	}
+}
@@ -0,0 +1,71 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAsyncCommands(t *testing.T) {
+	setup := func(handler AsyncHandlerFunc) (*env, *Handler, *Definition) {
+		env := setupTest()
+		h := &Handler{client: env.client, botUserID: "bot-id"}
+		h.ctx, h.cancel = context.WithCancel(context.Background())
+		return env, h, &Definition{Trigger: "report", AsyncHandler: handler}
+	}
+	args := &model.CommandArgs{UserId: "user-id", ChannelId: "channel-id", T: testTranslations("en")}
+
+	t.Run("posts in-channel results as the bot", func(t *testing.T) {
+		env, handler, definition := setup(func(ctx context.Context, args *model.CommandArgs, values *Values) (*model.CommandResponse, error) {
+			return &model.CommandResponse{ResponseType: model.CommandResponseTypeInChannel, Text: "Report ready"}, nil
+		})
+		env.api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.UserId == "bot-id" && post.ChannelId == "channel-id" && post.Message == "Report ready"
+		})).Return(&model.Post{}, nil).Once()
+
+		response, err := definition.execute(handler, args, nil, nil)
+		require.NoError(t, err)
+		assert.Equal(t, model.CommandResponseTypeEphemeral, response.ResponseType)
+		assert.Equal(t, "Working on `/report`. The result will be posted here once it is ready.", response.Text)
+
+		require.NoError(t, handler.Close())
+		env.api.AssertExpectations(t)
+	})
+
+	t.Run("surfaces errors to the user", func(t *testing.T) {
+		env, handler, definition := setup(func(ctx context.Context, args *model.CommandArgs, values *Values) (*model.CommandResponse, error) {
+			return nil, errors.New("service unavailable")
+		})
+		env.api.On("LogWarn", "Background slash command failed", "command", "/report", "user_id", "user-id", "error", mock.Anything).Return()
+		env.api.On("SendEphemeralPost", "user-id", mock.MatchedBy(func(post *model.Post) bool {
+			return post.Message == "`/report` failed: service unavailable"
+		})).Return(&model.Post{}).Once()
+
+		_, err := definition.execute(handler, args, nil, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, handler.Close())
+		env.api.AssertExpectations(t)
+	})
+
+	t.Run("cancels on close", func(t *testing.T) {
+		started := make(chan struct{})
+		env, handler, definition := setup(func(ctx context.Context, args *model.CommandArgs, values *Values) (*model.CommandResponse, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, nil
+		})
+
+		_, err := definition.execute(handler, args, nil, nil)
+		require.NoError(t, err)
+		<-started
+
+		require.NoError(t, handler.Close())
+		env.api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+}
@@ -0,0 +1,196 @@
+package command
+
+import (
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+// autocompletePath is the plugin-relative path serving dynamic list suggestions. The server
+// resolves it against the plugin's URL when fetching suggestions.
+const autocompletePath = "api/v1/autocomplete"
+
+// SuggestFunc returns the suggestions for a dynamic list argument given the partially typed
+// command. The args only carry the user, team, channel and root IDs of the invocation.
+type SuggestFunc func(args *model.CommandArgs, userInput string) ([]model.AutocompleteListItem, error)
+
+// Argument describes a positional argument or flag accepted by a command. It is used both to
+// generate the autocomplete data and to document the command.
+type Argument struct {
+	// Name identifies the argument. Flags are passed as `--name value`.
+	Name string
+
+	// Short is an optional single letter alias for a flag, passed as `-s value`.
+	Short string
+
+	// Type determines how the value is parsed and validated.
+	Type ArgumentType
+
+	// Default is used when an optional argument is not given.
+	Default string
+
+	// HelpText describes the argument.
+	HelpText string
+
+	// Hint is shown as a placeholder for text arguments, e.g. `@username`.
+	Hint string
+
+	// Required marks the argument as mandatory.
+	Required bool
+
+	// Variadic makes the last positional argument collect all remaining positional values.
+	Variadic bool
+
+	// Rest makes the last positional argument take the rest of the command as typed, e.g. for
+	// free text: spaces, quotes and words looking like flags are kept, as is `--`, unless it
+	// precedes the text.
+	Rest bool
+
+	// Pattern is a regular expression the autocomplete uses to validate text arguments.
+	Pattern string
+
+	// Options restricts the argument to a static list of values.
+	Options []model.AutocompleteListItem
+
+	// Suggest makes the argument a dynamic list, with suggestions served by the plugin.
+	Suggest SuggestFunc
+}
+
+// addTo adds the argument to the autocomplete data of the command at the given path. A named
+// argument is added when name is non-empty.
+func (a *Argument) addTo(data *model.AutocompleteData, commandPath []string, name string) {
+	switch {
+	case a.Suggest != nil:
+		data.AddNamedDynamicListArgument(name, a.HelpText, a.fetchURL(commandPath), a.Required)
+	case len(a.Options) > 0:
+		data.AddNamedStaticListArgument(name, a.HelpText, a.Required, a.Options)
+	case name == "":
+		// The server rejects optional positional text arguments.
+		data.AddTextArgument(a.HelpText, a.Hint, a.Pattern)
+	default:
+		data.AddNamedTextArgument(name, a.HelpText, a.Hint, a.Pattern, a.Required)
+	}
+}
+
+// fetchURL returns the URL serving the suggestions of a dynamic list argument, e.g.
+// `api/v1/autocomplete/hello/alias/remove/name`. The triggers are separated by slashes rather
+// than escaped spaces, which the server would escape again when resolving the URL.
+func (a *Argument) fetchURL(commandPath []string) string {
+	elements := []string{autocompletePath}
+	for _, trigger := range commandPath {
+		elements = append(elements, url.PathEscape(trigger))
+	}
+	return path.Join(append(elements, url.PathEscape(a.Name))...)
+}
+
+// autocompleteData generates the autocomplete data for this definition and its subcommands. The
+// path holds the triggers leading up to, but excluding, this definition.
+func (d *Definition) autocompleteData(commandPath []string) *model.AutocompleteData {
+	commandPath = append(commandPath, d.Trigger)
+
+	data := model.NewAutocompleteData(d.Trigger, d.Hint, d.Description)
+	if d.Permission != nil && d.Permission.Scope == model.PermissionScopeSystem {
+		data.RoleID = model.SystemAdminRoleId
+	}
+
+	// The server does not allow a command to have both arguments and subcommands, so a
+	// definition with subcommands only suggests those.
+	if subcommands := d.enabledSubcommands(); len(subcommands) > 0 {
+		for _, subcommand := range subcommands {
+			data.AddCommand(subcommand.autocompleteData(commandPath))
+		}
+		return data
+	}
+
+	for _, argument := range d.Arguments {
+		argument.addTo(data, commandPath, "")
+	}
+	for _, flag := range d.Flags {
+		flag.addTo(data, commandPath, flag.Name)
+	}
+
+	return data
+}
+
+// find returns the definition at the given path of triggers, or nil if there is none.
+func find(definitions []*Definition, commandPath []string) *Definition {
+	if len(commandPath) == 0 {
+		return nil
+	}
+
+	var definition *Definition
+	for _, d := range definitions {
+		if d.matches(commandPath[0]) {
+			definition = d
+			break
+		}
+	}
+
+	for _, trigger := range commandPath[1:] {
+		if definition == nil {
+			return nil
+		}
+		definition = definition.subcommand(trigger)
+	}
+
+	return definition
+}
+
+// declaredPath splits the fields of a command line, starting with its trigger, into the declared
+// triggers of the command they run, e.g. `hello alias add`, and the fields that follow. The path
+// is nil if the fields do not start with the trigger of one of the definitions.
+func declaredPath(definitions []*Definition, fields []string) ([]string, []string) {
+	if len(fields) == 0 {
+		return nil, fields
+	}
+	definition := find(definitions, []string{strings.TrimPrefix(fields[0], "/")})
+	if definition == nil {
+		return nil, fields
+	}
+
+	path := []string{definition.declaredTrigger()}
+	fields = fields[1:]
+	for len(fields) > 0 {
+		subcommand := definition.subcommand(fields[0])
+		if subcommand == nil {
+			break
+		}
+		definition = subcommand
+		path = append(path, definition.declaredTrigger())
+		fields = fields[1:]
+	}
+	return path, fields
+}
+
+// Suggest returns the suggestions for the dynamic list argument with the given name, belonging
+// to the command identified by its space separated path of triggers, e.g. `hello alias remove`.
+// The autocomplete route receives the path separated by slashes, see fetchURL. Like Handle, it
+// checks that the command is enabled and that the user may run it, suggesting nothing otherwise.
+func (c *Handler) Suggest(args *model.CommandArgs, command, argument, userInput string) ([]model.AutocompleteListItem, error) {
+	path := strings.Fields(command)
+	var definition *Definition
+	for depth := 1; depth <= len(path); depth++ {
+		definition = find(c.activeDefinitions(), path[:depth])
+		if definition == nil {
+			return nil, errors.Errorf("unknown command %q", command)
+		}
+		if definition.disabled || !c.authorize(definition, args, path[:depth]) {
+			return []model.AutocompleteListItem{}, nil
+		}
+	}
+	if definition == nil {
+		return nil, errors.Errorf("unknown command %q", command)
+	}
+
+	for _, a := range slices.Concat(definition.Arguments, definition.Flags) {
+		if a.Name == argument && a.Suggest != nil {
+			return a.Suggest(args, userInput)
+		}
+	}
+
+	return nil, errors.Errorf("unknown dynamic argument %q for command %q", argument, command)
+}
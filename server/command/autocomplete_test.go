@@ -0,0 +1,97 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutocompleteData(t *testing.T) {
+	levels := []model.AutocompleteListItem{{Item: "debug"}, {Item: "info"}}
+	suggestNames := func(args *model.CommandArgs, userInput string) ([]model.AutocompleteListItem, error) {
+		return []model.AutocompleteListItem{{Item: args.UserId + ":" + userInput}}, nil
+	}
+
+	definition := &Definition{
+		Trigger:     "hello",
+		Description: "Say hello to someone",
+		Hint:        "[@username]",
+		Arguments:   []*Argument{{Name: "username", HelpText: "Username to say hello to", Hint: "@username", Required: true}},
+		Subcommands: []*Definition{
+			{
+				Trigger:     "settings",
+				Description: "Manage your settings",
+				Subcommands: []*Definition{
+					{
+						Trigger:     "set",
+						Description: "Change a setting",
+						Arguments:   []*Argument{{Name: "name", HelpText: "Setting to change", Required: true, Suggest: suggestNames}},
+						Flags:       []*Argument{{Name: "level", HelpText: "Log level", Options: levels}},
+					},
+				},
+			},
+		},
+	}
+
+	expected := model.NewAutocompleteData("hello", "[@username]", "Say hello to someone")
+	settings := model.NewAutocompleteData("settings", "", "Manage your settings")
+	set := model.NewAutocompleteData("set", "", "Change a setting")
+	set.AddDynamicListArgument("Setting to change", "api/v1/autocomplete/hello/settings/set/name", true)
+	set.AddNamedStaticListArgument("level", "Log level", false, levels)
+	settings.AddCommand(set)
+	expected.AddCommand(settings)
+
+	data := definition.autocompleteData(nil)
+	require.NoError(t, data.IsValid())
+	assert.Equal(t, expected, data)
+
+	t.Run("suggest", func(t *testing.T) {
+		handler := &Handler{definitions: []*Definition{definition}}
+
+		items, err := handler.Suggest(&model.CommandArgs{UserId: "user-id"}, "hello settings set", "name", "/hello settings set f")
+		require.NoError(t, err)
+		assert.Equal(t, []model.AutocompleteListItem{{Item: "user-id:/hello settings set f"}}, items)
+
+		_, err = handler.Suggest(&model.CommandArgs{}, "hello settings set", "level", "")
+		assert.Error(t, err)
+
+		_, err = handler.Suggest(&model.CommandArgs{}, "hello unknown", "name", "")
+		assert.Error(t, err)
+	})
+
+	t.Run("suggest nothing for commands the user may not run", func(t *testing.T) {
+		suggestNames := func(args *model.CommandArgs, userInput string) ([]model.AutocompleteListItem, error) {
+			t.Fatal("suggested values for a command the user may not run")
+			return nil, nil
+		}
+		ok := func(*model.CommandArgs, *Values) (*model.CommandResponse, error) {
+			return &model.CommandResponse{}, nil
+		}
+		restricted := &Definition{
+			Trigger: "hello",
+			Subcommands: []*Definition{
+				{Trigger: "admin", Permission: model.PermissionManageSystem, Subcommands: []*Definition{
+					{Trigger: "set", Arguments: []*Argument{{Name: "name", Suggest: suggestNames}}, Handler: ok},
+				}},
+				{Trigger: "settings", Arguments: []*Argument{{Name: "name", Suggest: suggestNames}}, Handler: ok},
+			},
+		}
+
+		env := setupTest()
+		env.api.On("HasPermissionTo", "user-id", model.PermissionManageSystem).Return(false)
+		env.api.On("LogWarn", "Slash command not permitted",
+			"audit", true, "command", "/hello admin", "permission", model.PermissionManageSystem.Id,
+			"user_id", "user-id", "team_id", "", "channel_id", "").Return().Once()
+		handler := &Handler{client: env.client, definitions: []*Definition{restricted}}
+		handler.active = []*Definition{configureDefinition(restricted, Config{Disabled: []string{"hello settings"}}, "hello", nil, nil)}
+
+		for _, command := range []string{"hello admin set", "hello settings"} {
+			items, err := handler.Suggest(&model.CommandArgs{UserId: "user-id"}, command, "name", "")
+			require.NoError(t, err)
+			assert.Empty(t, items, command)
+		}
+		env.api.AssertExpectations(t)
+	})
+}
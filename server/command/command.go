@@ -1,71 +1,311 @@
 package command
 
 import (
-	"fmt"
+	"context"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/mattermost/mattermost/server/public/shared/i18n"
+
+	"github.com/mattermost/mattermost-plugin-starter-template/server/store/kvstore"
 )
 
 type Handler struct {
 	client *pluginapi.Client
+
+	// kvstore is used to persist command data.
+	kvstore kvstore.KVStore
+
+	// pluginID is the ID of the plugin, used to build URLs pointing back to the plugin.
+	pluginID string
+
+	// botUserID is the ID of the plugin's bot, used to send direct messages.
+	botUserID string
+
+	// translations translate the replies into the locale of the invoking user.
+	translations i18n.TranslationFuncByLocal
+
+	// definitions are the top-level slash commands of this handler, as declared.
+	definitions []*Definition
+
+	// activeLock synchronizes access to active.
+	activeLock sync.RWMutex
+
+	// active are the definitions as configured, and registered with the server. Consult
+	// activeDefinitions.
+	active []*Definition
+
+	// dialogs are the interactive dialogs of all definitions, by ID.
+	dialogs map[string]*Dialog
+
+	// actions are the interactive message actions of all definitions, by ID.
+	actions map[string]*Action
+
+	// middleware wraps the execution of every command, outermost first.
+	middleware []Middleware
+
+	// rateLimiter limits how often users run commands, or is nil. Consult LimitRate.
+	rateLimiter RateLimiter
+
+	// observe is notified of every invocation, or is nil. Consult Observe.
+	observe ObserveFunc
+
+	// ctx is cancelled by Close, stopping the commands running in the background.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// background tracks the commands running in the background.
+	background sync.WaitGroup
+
+	// signingKeyLock synchronizes access to the signingKey.
+	signingKeyLock sync.Mutex
+
+	// signingKey signs the context of interactive message actions. Consult actionSigningKey.
+	signingKey []byte
 }
 
 type Command interface {
 	Handle(args *model.CommandArgs) (*model.CommandResponse, error)
-	executeHelloCommand(args *model.CommandArgs) *model.CommandResponse
+	Use(middleware ...Middleware)
+	LimitRate(limiter RateLimiter)
+	Observe(observe ObserveFunc)
+	Configure(config Config)
+	Close() error
+	Suggest(args *model.CommandArgs, command, argument, userInput string) ([]model.AutocompleteListItem, error)
+	SubmitDialog(request *model.SubmitDialogRequest) (*model.SubmitDialogResponse, error)
+	HandleAction(request *model.PostActionIntegrationRequest) (*model.PostActionIntegrationResponse, error)
+	RunScheduledCommands(now time.Time) error
 }
 
-const helloCommandTrigger = "hello"
-
-// Register all your slash commands in the NewCommandHandler function.
-func NewCommandHandler(client *pluginapi.Client) Command {
-	err := client.SlashCommand.Register(&model.Command{
-		Trigger:          helloCommandTrigger,
-		AutoComplete:     true,
-		AutoCompleteDesc: "Say hello to someone",
-		AutoCompleteHint: "[@username]",
-		AutocompleteData: model.NewAutocompleteData(helloCommandTrigger, "[@username]", "Username to say hello to"),
-	})
-	if err != nil {
-		client.Log.Error("Failed to register command", "error", err)
+// HelloCommandTrigger is the default trigger of the hello command.
+const HelloCommandTrigger = "hello"
+
+// Register all your slash commands in the NewCommandHandler function. They are registered with
+// the server as configured.
+func NewCommandHandler(client *pluginapi.Client, store kvstore.KVStore, pluginID, botUserID string, translations i18n.TranslationFuncByLocal, config Config) Command {
+	c := &Handler{
+		client:       client,
+		kvstore:      store,
+		pluginID:     pluginID,
+		botUserID:    botUserID,
+		translations: translations,
+		dialogs:      map[string]*Dialog{},
+		actions:      map[string]*Action{},
 	}
-	return &Handler{
-		client: client,
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	c.Use(
+		LoggingMiddleware(client.Log),
+		RecoveryMiddleware(client.Log),
+	)
+
+	c.definitions = []*Definition{
+		{
+			Trigger:     HelloCommandTrigger,
+			Description: "Say hello to someone",
+			Hint:        "[@username...] [~channel...]",
+			Arguments: []*Argument{
+				{Name: "targets", HelpText: "Users and channels to say hello to", Hint: "@username ~channel", Required: true, Variadic: true},
+			},
+			Flags: []*Argument{
+				{Name: "dm", Short: "d", Type: ArgumentTypeBool, HelpText: "Send the greeting from the bot instead of posting it here"},
+			},
+			Handler: c.executeHelloCommand,
+			Subcommands: []*Definition{
+				{
+					Trigger:     "wave",
+					Description: "Wave at someone, letting everyone in the channel wave back",
+					Hint:        "<@username>",
+					Arguments: []*Argument{
+						{Name: "user", HelpText: "The user to wave at", Hint: "@username", Type: ArgumentTypeUser, Required: true},
+					},
+					Handler: c.executeWaveCommand,
+					Actions: []*Action{{ID: waveBackActionID, Handle: c.handleWaveBack}},
+				},
+				{
+					Trigger:     "template",
+					Description: "Manage the template of your greetings",
+					Subcommands: []*Definition{
+						{
+							Trigger:     "set",
+							Description: "Set your greeting template, e.g. `Hi {{.Recipient}}, {{.Sender}} says hello from {{.Channel}}!`",
+							Hint:        "<template>",
+							Arguments: []*Argument{
+								{Name: "template", HelpText: "A Go template using {{.Recipient}}, {{.Sender}} and {{.Channel}}", Hint: "template", Required: true, Rest: true},
+							},
+							Handler: c.executeTemplateSetCommand,
+						},
+						{
+							Trigger:     "edit",
+							Description: "Edit your greeting template in a dialog",
+							Handler:     c.executeTemplateEditCommand,
+							Dialogs: []*Dialog{
+								{
+									ID: templateDialogID,
+									Dialog: model.Dialog{
+										Title:       "Greeting template",
+										SubmitLabel: "Save",
+										Elements: []model.DialogElement{
+											{
+												DisplayName: "Template",
+												Name:        "template",
+												Type:        "textarea",
+												MaxLength:   maxGreetingTemplateLength,
+												HelpText:    "A Go template using {{.Recipient}}, {{.Sender}} and {{.Channel}}",
+											},
+										},
+									},
+									Validate: c.validateTemplateDialog,
+									Submit:   c.submitTemplateDialog,
+								},
+							},
+						},
+						{Trigger: "show", Description: "Show your greeting template", Handler: c.executeTemplateShowCommand},
+						{Trigger: "clear", Description: "Go back to the default greeting", Handler: c.executeTemplateClearCommand},
+					},
+				},
+				{
+					Trigger:     "schedule",
+					Description: "Run a command later, e.g. `in 2h @alice` or `tomorrow 9:00 --dm @alice`",
+					Hint:        "<when> <command>",
+					Raw:         true,
+					Handler:     c.executeScheduleCommand,
+					Subcommands: []*Definition{
+						{Trigger: "list", Description: "List your scheduled commands", Handler: c.executeScheduleListCommand},
+						{
+							Trigger:     "cancel",
+							Description: "Cancel a scheduled command",
+							Hint:        "<id>",
+							Arguments: []*Argument{
+								{Name: "id", HelpText: "The scheduled command to cancel", Required: true, Suggest: c.suggestScheduledCommands},
+							},
+							Handler: c.executeScheduleCancelCommand,
+						},
+					},
+				},
+				{
+					Trigger:     "alias",
+					Description: "Manage shortcuts for long commands",
+					Subcommands: []*Definition{
+						{
+							Trigger:     "add",
+							Description: "Add an alias, e.g. `standup @alice @bob` to say hello to both with `/hello standup`",
+							Hint:        "<name> <expansion>",
+							Arguments:   aliasArguments(true),
+							Handler:     c.executeAliasAddCommand,
+						},
+						{Trigger: "remove", Description: "Remove an alias", Hint: "<name>", Arguments: aliasArguments(false), Handler: c.executeAliasRemoveCommand},
+						{Trigger: "list", Description: "List your aliases and those of the team", Handler: c.executeAliasListCommand},
+						{
+							Trigger:     "team",
+							Description: "Manage the aliases shared by everyone in the team",
+							Permission:  model.PermissionManageTeam,
+							Subcommands: []*Definition{
+								{Trigger: "add", Description: "Add a team alias", Hint: "<name> <expansion>", Arguments: aliasArguments(true), Handler: c.executeTeamAliasAddCommand},
+								{Trigger: "remove", Description: "Remove a team alias", Hint: "<name>", Arguments: aliasArguments(false), Handler: c.executeTeamAliasRemoveCommand},
+							},
+						},
+					},
+				},
+			},
+		},
 	}
+
+	indexDialogs(c.dialogs, c.definitions)
+	indexActions(c.actions, c.definitions)
+
+	c.Configure(config)
+
+	return c
+}
+
+// Use appends middleware wrapping the execution of every command. It must be called before the
+// handler starts serving commands, typically right after NewCommandHandler in OnActivate.
+func (c *Handler) Use(middleware ...Middleware) {
+	c.middleware = append(c.middleware, middleware...)
+}
+
+// LimitRate limits how often users run commands, answering the invocations over the limits with
+// an ephemeral message. Limits apply to the commands once their aliases are expanded, and not to
+// the runs of scheduled commands. It must be called before the handler starts serving commands,
+// like Use.
+func (c *Handler) LimitRate(limiter RateLimiter) {
+	c.rateLimiter = limiter
+}
+
+// Observe notifies observe of every invocation once it completes, e.g. to record metrics. Unlike
+// middleware, it tells the invocations rejected by the rate limits and the panics recovered from
+// apart from the successful ones. It must be called before the handler starts serving commands,
+// like Use.
+func (c *Handler) Observe(observe ObserveFunc) {
+	c.observe = observe
 }
 
 // ExecuteCommand hook calls this method to execute the commands that were registered in the NewCommandHandler function.
+// Replies are translated with args.T, which defaults to the locale of the invoking user.
 func (c *Handler) Handle(args *model.CommandArgs) (*model.CommandResponse, error) {
-	fields := strings.Fields(args.Command)
-	if len(fields) == 0 {
+	return c.handle(args, true)
+}
+
+// handle executes the command through the middleware, limiting its rate if limitRate is set.
+func (c *Handler) handle(args *model.CommandArgs, limitRate bool) (*model.CommandResponse, error) {
+	start := time.Now()
+	outcome := OutcomeSuccess
+
+	handle := func(args *model.CommandArgs) (*model.CommandResponse, error) {
+		defer func() {
+			// The panic is recorded before the middleware recovers from it.
+			if r := recover(); r != nil {
+				outcome = OutcomePanic
+				panic(r)
+			}
+		}()
+		return c.dispatch(args)
+	}
+	if limitRate && c.rateLimiter != nil {
+		handle = rateLimitMiddleware(c.rateLimiter, c.activeDefinitions(), func() { outcome = OutcomeRateLimited })(handle)
+	}
+	handle = c.expandAliases(handle)
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		handle = c.middleware[i](handle)
+	}
+
+	args = c.localize(args)
+	response, err := handle(args)
+	if err != nil && outcome == OutcomeSuccess {
+		outcome = OutcomeError
+	}
+	if c.observe != nil {
+		c.observe(args, trigger(args), time.Since(start), outcome)
+	}
+	return response, err
+}
+
+// dispatch runs the handler of the invoked command.
+func (c *Handler) dispatch(args *model.CommandArgs) (*model.CommandResponse, error) {
+	T := translate(args)
+
+	fields, err := tokenize(args.Command)
+	if err != nil {
 		return &model.CommandResponse{
 			ResponseType: model.CommandResponseTypeEphemeral,
-			Text:         "Empty command",
+			Text:         T("command.parse_error", map[string]any{"Error": err.Error()}),
 		}, nil
 	}
-	trigger := strings.TrimPrefix(fields[0], "/")
-	switch trigger {
-	case helloCommandTrigger:
-		return c.executeHelloCommand(args), nil
-	default:
+	if len(fields) == 0 {
 		return &model.CommandResponse{
 			ResponseType: model.CommandResponseTypeEphemeral,
-			Text:         fmt.Sprintf("Unknown command: %s", args.Command),
+			Text:         T("command.empty"),
 		}, nil
 	}
-}
-
-func (c *Handler) executeHelloCommand(args *model.CommandArgs) *model.CommandResponse {
-	if len(strings.Fields(args.Command)) < 2 {
-		return &model.CommandResponse{
-			ResponseType: model.CommandResponseTypeEphemeral,
-			Text:         "Please specify a username",
-		}
+	if definition := find(c.activeDefinitions(), []string{strings.TrimPrefix(fields[0], "/")}); definition != nil {
+		return definition.execute(c, args, nil, fields[1:])
 	}
-	username := strings.Fields(args.Command)[1]
 	return &model.CommandResponse{
-		Text: "Hello, " + username,
-	}
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         T("command.unknown", map[string]any{"Command": args.Command}),
+	}, nil
 }
@@ -1,12 +1,17 @@
 package command
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
 	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/mattermost/mattermost/server/public/shared/i18n"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mattermost/mattermost-plugin-starter-template/server/store/kvstore"
 )
 
 type env struct {
@@ -14,6 +19,15 @@ type env struct {
 	api    *plugintest.API
 }
 
+// testTranslations are the server translations bundled with the plugin.
+var testTranslations = func() i18n.TranslationFuncByLocal {
+	translations, err := i18n.GetTranslationFuncForDir(filepath.Join("..", "..", "assets", "i18n"))
+	if err != nil {
+		panic(err)
+	}
+	return translations
+}()
+
 func setupTest() *env {
 	api := &plugintest.API{}
 	driver := &plugintest.Driver{}
@@ -29,19 +43,67 @@ func TestHelloCommand(t *testing.T) {
 	assert := assert.New(t)
 	env := setupTest()
 
+	autocompleteData := model.NewAutocompleteData("hello", "[@username...] [~channel...]", "Say hello to someone")
+	waveData := model.NewAutocompleteData("wave", "<@username>", "Wave at someone, letting everyone in the channel wave back")
+	waveData.AddTextArgument("The user to wave at", "@username", "")
+	autocompleteData.AddCommand(waveData)
+	templateData := model.NewAutocompleteData("template", "", "Manage the template of your greetings")
+	setData := model.NewAutocompleteData("set", "<template>", "Set your greeting template, e.g. `Hi {{.Recipient}}, {{.Sender}} says hello from {{.Channel}}!`")
+	setData.AddTextArgument("A Go template using {{.Recipient}}, {{.Sender}} and {{.Channel}}", "template", "")
+	templateData.AddCommand(setData)
+	templateData.AddCommand(model.NewAutocompleteData("edit", "", "Edit your greeting template in a dialog"))
+	templateData.AddCommand(model.NewAutocompleteData("show", "", "Show your greeting template"))
+	templateData.AddCommand(model.NewAutocompleteData("clear", "", "Go back to the default greeting"))
+	autocompleteData.AddCommand(templateData)
+	scheduleData := model.NewAutocompleteData("schedule", "<when> <command>", "Run a command later, e.g. `in 2h @alice` or `tomorrow 9:00 --dm @alice`")
+	scheduleData.AddCommand(model.NewAutocompleteData("list", "", "List your scheduled commands"))
+	cancelData := model.NewAutocompleteData("cancel", "<id>", "Cancel a scheduled command")
+	cancelData.AddDynamicListArgument("The scheduled command to cancel", "api/v1/autocomplete/hello/schedule/cancel/id", true)
+	scheduleData.AddCommand(cancelData)
+	autocompleteData.AddCommand(scheduleData)
+	aliasData := model.NewAutocompleteData("alias", "", "Manage shortcuts for long commands")
+	addAliasData := func(helpText string) *model.AutocompleteData {
+		data := model.NewAutocompleteData("add", "<name> <expansion>", helpText)
+		data.AddTextArgument("The name of the alias", "name", "")
+		data.AddTextArgument("What the alias stands for, after -- if it has flags. $1 to $9 and $@ are replaced by the arguments of the alias", "expansion", "")
+		return data
+	}
+	removeAliasData := func(helpText string) *model.AutocompleteData {
+		data := model.NewAutocompleteData("remove", "<name>", helpText)
+		data.AddTextArgument("The name of the alias", "name", "")
+		return data
+	}
+	aliasData.AddCommand(addAliasData("Add an alias, e.g. `standup @alice @bob` to say hello to both with `/hello standup`"))
+	aliasData.AddCommand(removeAliasData("Remove an alias"))
+	aliasData.AddCommand(model.NewAutocompleteData("list", "", "List your aliases and those of the team"))
+	teamAliasData := model.NewAutocompleteData("team", "", "Manage the aliases shared by everyone in the team")
+	teamAliasData.AddCommand(addAliasData("Add a team alias"))
+	teamAliasData.AddCommand(removeAliasData("Remove a team alias"))
+	aliasData.AddCommand(teamAliasData)
+	autocompleteData.AddCommand(aliasData)
 	env.api.On("RegisterCommand", &model.Command{
-		Trigger:          helloCommandTrigger,
+		Trigger:          HelloCommandTrigger,
 		AutoComplete:     true,
 		AutoCompleteDesc: "Say hello to someone",
-		AutoCompleteHint: "[@username]",
-		AutocompleteData: model.NewAutocompleteData("hello", "[@username]", "Username to say hello to"),
+		AutoCompleteHint: "[@username...] [~channel...]",
+		AutocompleteData: autocompleteData,
 	}).Return(nil)
-	cmdHandler := NewCommandHandler(env.client)
+	env.api.On("KVGet", "template_key-").Return(nil, nil)
+	env.api.On("KVGet", "user_aliases-").Return(nil, nil)
+	env.api.On("GetUser", "").Return(&model.User{Locale: "en"}, nil)
+	env.api.On("GetUserByUsername", "world").Return(&model.User{Id: "world-id", Username: "world"}, nil)
+	env.api.On("LogInfo", "Slash command executed", "trigger", "hello", "user_id", "", "channel_id", "", "team_id", "", "duration_ms", mock.AnythingOfType("int64")).Return()
+	cmdHandler := NewCommandHandler(env.client, kvstore.NewKVStore(env.client), "plugin-id", "bot-user-id", testTranslations, Config{})
 
 	args := &model.CommandArgs{
 		Command: "/hello world",
 	}
 	response, err := cmdHandler.Handle(args)
 	assert.Nil(err)
-	assert.Equal("Hello, world", response.Text)
+	assert.Equal("Hello, @world", response.Text)
+
+	response, err = cmdHandler.Handle(&model.CommandArgs{Command: "/hello"})
+	assert.Nil(err)
+	assert.Equal(model.CommandResponseTypeEphemeral, response.ResponseType)
+	assert.Contains(response.Text, "Missing required argument `<targets>`.")
 }
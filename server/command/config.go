@@ -0,0 +1,118 @@
+package command
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// triggerPattern matches the triggers administrators may configure.
+var triggerPattern = regexp.MustCompile(`^[a-z0-9_.-]{1,128}$`)
+
+// Config configures the commands of a Handler. The zero value enables every command with its
+// default trigger.
+type Config struct {
+	// Triggers maps the default trigger of top-level commands to the trigger registered
+	// instead, e.g. to avoid a collision with the command of another plugin.
+	Triggers map[string]string
+
+	// Disabled holds the paths of the disabled commands, e.g. `hello` or `hello alias`, using
+	// either the default or the configured trigger. Disabled top-level commands are
+	// unregistered, while disabled subcommands are hidden from autocomplete and rejected.
+	Disabled []string
+}
+
+// isDisabled returns true if the command at either path is disabled.
+func (config Config) isDisabled(defaultPath, activePath []string) bool {
+	return slices.ContainsFunc(config.Disabled, func(disabled string) bool {
+		fields := strings.Fields(strings.ToLower(disabled))
+		return slices.Equal(fields, defaultPath) || slices.Equal(fields, activePath)
+	})
+}
+
+// Configure applies the configuration, registering the triggers of the enabled top-level
+// commands and unregistering those no longer in use. It is safe to call while commands are
+// being handled, typically from OnConfigurationChange.
+func (c *Handler) Configure(config Config) {
+	active := []*Definition{}
+	for _, definition := range c.definitions {
+		trigger := definition.Trigger
+		if configured := strings.TrimPrefix(strings.TrimSpace(config.Triggers[trigger]), "/"); configured != "" {
+			if triggerPattern.MatchString(configured) && find(active, []string{configured}) == nil {
+				trigger = configured
+			} else {
+				c.client.Log.Error("Invalid command trigger, using the default trigger instead", "trigger", configured, "default", definition.Trigger)
+			}
+		}
+		if config.isDisabled([]string{definition.Trigger}, []string{trigger}) {
+			continue
+		}
+		active = append(active, configureDefinition(definition, config, trigger, nil, nil))
+	}
+
+	c.activeLock.Lock()
+	previous := c.active
+	c.active = active
+	c.activeLock.Unlock()
+
+	for _, definition := range previous {
+		if find(active, []string{definition.Trigger}) != nil {
+			continue
+		}
+		if err := c.client.SlashCommand.Unregister("", definition.Trigger); err != nil {
+			c.client.Log.Error("Failed to unregister command", "trigger", definition.Trigger, "error", err)
+		}
+	}
+
+	for _, definition := range active {
+		autocompleteData := definition.autocompleteData(nil)
+		if err := autocompleteData.IsValid(); err != nil {
+			c.client.Log.Error("Invalid autocomplete data", "trigger", definition.Trigger, "error", err)
+			autocompleteData = nil
+		}
+
+		err := c.client.SlashCommand.Register(&model.Command{
+			Trigger:          definition.Trigger,
+			AutoComplete:     true,
+			AutoCompleteDesc: definition.Description,
+			AutoCompleteHint: definition.Hint,
+			AutocompleteData: autocompleteData,
+		})
+		if err != nil {
+			c.client.Log.Error("Failed to register command", "trigger", definition.Trigger, "error", err)
+		}
+	}
+}
+
+// configureDefinition copies the definition tree with the given trigger, marking the disabled
+// subcommands. The paths hold the default and configured triggers leading up to, but excluding,
+// the definition.
+func configureDefinition(definition *Definition, config Config, trigger string, defaultPath, activePath []string) *Definition {
+	configured := *definition
+	configured.Trigger = trigger
+	configured.defaultTrigger = definition.Trigger
+	defaultPath = append(slices.Clone(defaultPath), definition.Trigger)
+	activePath = append(slices.Clone(activePath), trigger)
+	configured.disabled = config.isDisabled(defaultPath, activePath)
+
+	configured.Subcommands = make([]*Definition, 0, len(definition.Subcommands))
+	for _, subcommand := range definition.Subcommands {
+		configured.Subcommands = append(configured.Subcommands, configureDefinition(subcommand, config, subcommand.Trigger, defaultPath, activePath))
+	}
+
+	return &configured
+}
+
+// activeDefinitions returns the top-level commands as configured. Until Configure is called, all
+// commands are active with their default triggers.
+func (c *Handler) activeDefinitions() []*Definition {
+	c.activeLock.RLock()
+	defer c.activeLock.RUnlock()
+
+	if c.active == nil {
+		return c.definitions
+	}
+	return c.active
+}
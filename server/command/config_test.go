@@ -0,0 +1,76 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigure(t *testing.T) {
+	ok := func(args *model.CommandArgs, values *Values) (*model.CommandResponse, error) {
+		return &model.CommandResponse{Text: "ok"}, nil
+	}
+	setup := func() (*env, *Handler) {
+		env := setupTest()
+		handler := &Handler{client: env.client}
+		handler.definitions = []*Definition{
+			{
+				Trigger: "hello",
+				Handler: ok,
+				Subcommands: []*Definition{
+					{Trigger: "alias", Handler: ok},
+					{Trigger: "template", Handler: ok},
+				},
+			},
+			{Trigger: "bye", Handler: ok},
+		}
+		return env, handler
+	}
+	registered := func(trigger string) any {
+		return mock.MatchedBy(func(command *model.Command) bool { return command.Trigger == trigger })
+	}
+
+	t.Run("renames and disables commands", func(t *testing.T) {
+		env, handler := setup()
+		env.api.On("RegisterCommand", registered("hello")).Return(nil).Once()
+		env.api.On("RegisterCommand", registered("bye")).Return(nil).Once()
+		handler.Configure(Config{})
+
+		env.api.On("RegisterCommand", mock.MatchedBy(func(command *model.Command) bool {
+			return command.Trigger == "hi" &&
+				len(command.AutocompleteData.SubCommands) == 1 &&
+				command.AutocompleteData.SubCommands[0].Trigger == "template"
+		})).Return(nil).Once()
+		env.api.On("UnregisterCommand", "", "hello").Return(nil).Once()
+		env.api.On("UnregisterCommand", "", "bye").Return(nil).Once()
+		handler.Configure(Config{
+			Triggers: map[string]string{"hello": "/hi"},
+			Disabled: []string{"bye", "hi alias"},
+		})
+		env.api.AssertExpectations(t)
+
+		response, err := handler.dispatch(&model.CommandArgs{Command: "/hi template", T: testTranslations("en")})
+		require.NoError(t, err)
+		assert.Equal(t, "ok", response.Text)
+
+		response, err = handler.dispatch(&model.CommandArgs{Command: "/hi alias", T: testTranslations("en")})
+		require.NoError(t, err)
+		assert.Equal(t, "`/hi alias` is disabled by your system administrator.", response.Text)
+
+		response, err = handler.dispatch(&model.CommandArgs{Command: "/hello", T: testTranslations("en")})
+		require.NoError(t, err)
+		assert.Equal(t, "Unknown command: /hello", response.Text)
+	})
+
+	t.Run("rejects invalid triggers", func(t *testing.T) {
+		env, handler := setup()
+		env.api.On("LogError", "Invalid command trigger, using the default trigger instead", "trigger", "say hi", "default", "hello").Return().Once()
+		env.api.On("RegisterCommand", registered("hello")).Return(nil).Once()
+		env.api.On("RegisterCommand", registered("bye")).Return(nil).Once()
+		handler.Configure(Config{Triggers: map[string]string{"hello": "say hi"}})
+		env.api.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,225 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/shared/i18n"
+)
+
+// HandlerFunc executes a slash command. The values hold the parsed arguments of the command
+// being executed, as well as the raw words that follow its trigger, e.g. for `/hello settings set
+// foo` dispatched to the `set` subcommand, values.Fields is []string{"foo"}.
+type HandlerFunc func(args *model.CommandArgs, values *Values) (*model.CommandResponse, error)
+
+// Definition describes a node in the slash command tree. Top-level definitions are registered
+// with the server as slash commands, while their subcommands are dispatched by Handle.
+//
+// A definition without a handler only groups its subcommands, and invoking it responds with the
+// generated usage text. A definition with both a handler and subcommands receives any input that
+// does not match one of its subcommands.
+type Definition struct {
+	// Trigger is the word that selects this command, e.g. `hello` or `settings`.
+	Trigger string
+
+	// Description is a short summary of what the command does.
+	Description string
+
+	// Hint describes the arguments expected by the command, e.g. `[@username]`.
+	Hint string
+
+	// Arguments are the positional arguments accepted by the command, in order.
+	Arguments []*Argument
+
+	// Flags are the named arguments accepted by the command, passed as `--name value`.
+	Flags []*Argument
+
+	// Raw skips parsing the arguments and flags, which then only document the command: the
+	// handler gets the words following the trigger in values.Fields. It suits commands taking
+	// another command as argument, whose flags must not be parsed as their own.
+	Raw bool
+
+	// Permission, if set, is required to run the command and any of its subcommands. It is
+	// checked against the system, or the team or channel the command was run in, depending on
+	// its scope.
+	Permission *model.Permission
+
+	// Handler executes the command.
+	Handler HandlerFunc
+
+	// AsyncHandler executes the command in the background instead of Handler, for commands
+	// calling slow services. The user is immediately told the command is running, and the
+	// response is delivered once the handler returns: in-channel responses are posted by the
+	// plugin's bot, other responses are sent as ephemeral posts, as are errors.
+	AsyncHandler AsyncHandlerFunc
+
+	// Dialogs are the interactive dialogs the handler may open, with their submission handlers.
+	Dialogs []*Dialog
+
+	// Actions handle the interactive message buttons and menus the handler may post.
+	Actions []*Action
+
+	// Subcommands are the commands nested below this one.
+	Subcommands []*Definition
+
+	// disabled is set by Configure on the subcommands disabled by the administrator.
+	disabled bool
+
+	// defaultTrigger is set by Configure to the trigger the definition is declared with. Consult
+	// declaredTrigger.
+	defaultTrigger string
+}
+
+// declaredTrigger returns the trigger the definition is declared with, even if Configure
+// replaced it with another.
+func (d *Definition) declaredTrigger() string {
+	if d.defaultTrigger != "" {
+		return d.defaultTrigger
+	}
+	return d.Trigger
+}
+
+// matches returns true if the trigger selects the definition. Triggers are case insensitive, at
+// the top level as for subcommands.
+func (d *Definition) matches(trigger string) bool {
+	return strings.EqualFold(d.Trigger, trigger)
+}
+
+// subcommand returns the direct subcommand with the given trigger, or nil if there is none.
+func (d *Definition) subcommand(trigger string) *Definition {
+	for _, subcommand := range d.Subcommands {
+		if subcommand.matches(trigger) {
+			return subcommand
+		}
+	}
+	return nil
+}
+
+// enabledSubcommands returns the subcommands that are not disabled.
+func (d *Definition) enabledSubcommands() []*Definition {
+	enabled := make([]*Definition, 0, len(d.Subcommands))
+	for _, subcommand := range d.Subcommands {
+		if !subcommand.disabled {
+			enabled = append(enabled, subcommand)
+		}
+	}
+	return enabled
+}
+
+// runnable returns true if the definition has a handler, rather than only grouping subcommands.
+func (d *Definition) runnable() bool {
+	return d.Handler != nil || d.AsyncHandler != nil
+}
+
+// execute walks the command tree along fields and runs the deepest matching handler with its
+// parsed arguments. The path holds the triggers leading up to, but excluding, this definition.
+func (d *Definition) execute(c *Handler, args *model.CommandArgs, path []string, fields []string) (*model.CommandResponse, error) {
+	path = append(path, d.Trigger)
+	T := translate(args)
+
+	if d.disabled {
+		return &model.CommandResponse{
+			ResponseType: model.CommandResponseTypeEphemeral,
+			Text:         T("command.disabled", map[string]any{"Command": joinPath(path)}),
+		}, nil
+	}
+
+	if !c.authorize(d, args, path) {
+		return &model.CommandResponse{
+			ResponseType: model.CommandResponseTypeEphemeral,
+			Text:         T("command.not_permitted"),
+		}, nil
+	}
+
+	if len(fields) > 0 {
+		if subcommand := d.subcommand(fields[0]); subcommand != nil {
+			return subcommand.execute(c, args, path, fields[1:])
+		}
+	}
+
+	if d.runnable() {
+		values := &Values{Fields: fields, values: map[string]any{}}
+		var err error
+		if !d.Raw {
+			values, err = c.parse(d, args, fields)
+		}
+		if err != nil {
+			return &model.CommandResponse{
+				ResponseType: model.CommandResponseTypeEphemeral,
+				Text:         fmt.Sprintf("%s\n\n%s", err.Error(), d.usage(T, path)),
+			}, nil
+		}
+		if d.AsyncHandler != nil {
+			return c.runAsync(d, args, values, path), nil
+		}
+		return d.Handler(args, values)
+	}
+
+	text := d.usage(T, path)
+	if len(fields) > 0 {
+		text = T("command.unknown_subcommand", map[string]any{"Subcommand": fields[0]}) + "\n\n" + text
+	}
+
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         text,
+	}, nil
+}
+
+// usage generates the help text for this definition, given the full path of triggers leading to
+// and including it. Descriptions and help texts are not translated.
+func (d *Definition) usage(T i18n.TranslateFunc, path []string) string {
+	command := "/" + joinPath(path)
+
+	subcommands := d.enabledSubcommands()
+
+	var sb strings.Builder
+	if len(subcommands) == 0 {
+		sb.WriteString(T("command.usage", map[string]any{"Usage": strings.TrimSpace(command + " " + d.Hint)}))
+		if d.Description != "" {
+			fmt.Fprintf(&sb, "\n%s", d.Description)
+		}
+		if len(d.Flags) > 0 {
+			sb.WriteString("\n\n" + T("command.usage.flags"))
+			for _, flag := range d.Flags {
+				name := "--" + flag.Name
+				if flag.Short != "" {
+					name += ", -" + flag.Short
+				}
+				fmt.Fprintf(&sb, "\n- `%s`", name)
+				if flag.HelpText != "" {
+					fmt.Fprintf(&sb, ": %s", flag.HelpText)
+				}
+				if flag.Default != "" {
+					sb.WriteString(" " + T("command.usage.default", map[string]any{"Default": flag.Default}))
+				}
+			}
+		}
+		return sb.String()
+	}
+
+	if d.runnable() && d.Hint != "" {
+		sb.WriteString(T("command.usage.with_subcommands", map[string]any{"Usage": command + " " + d.Hint, "Command": command}))
+	} else {
+		sb.WriteString(T("command.usage", map[string]any{"Usage": command + " <subcommand>"}))
+	}
+	if d.Description != "" {
+		fmt.Fprintf(&sb, "\n%s", d.Description)
+	}
+
+	sb.WriteString("\n\n" + T("command.usage.subcommands"))
+	for _, subcommand := range subcommands {
+		fmt.Fprintf(&sb, "\n- `%s`", strings.TrimSpace(subcommand.Trigger+" "+subcommand.Hint))
+		if subcommand.Description != "" {
+			fmt.Fprintf(&sb, ": %s", subcommand.Description)
+		}
+	}
+
+	return sb.String()
+}
+
+// joinPath joins the triggers of a command path, e.g. `hello settings set`.
+func joinPath(path []string) string {
+	return strings.Join(path, " ")
+}
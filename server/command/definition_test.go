@@ -0,0 +1,80 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefinitionExecute(t *testing.T) {
+	respond := func(text string) HandlerFunc {
+		return func(args *model.CommandArgs, values *Values) (*model.CommandResponse, error) {
+			return &model.CommandResponse{Text: text + ":" + strings.Join(values.Fields, ",")}, nil
+		}
+	}
+
+	root := &Definition{
+		Trigger:     "hello",
+		Description: "Say hello to someone",
+		Hint:        "[@username]",
+		Arguments:   []*Argument{{Name: "username"}},
+		Handler:     respond("hello"),
+		Subcommands: []*Definition{
+			{
+				Trigger:     "settings",
+				Description: "Manage your settings",
+				Subcommands: []*Definition{
+					{
+						Trigger:     "set",
+						Hint:        "<key> <value>",
+						Description: "Change a setting",
+						Arguments:   []*Argument{{Name: "key"}, {Name: "value"}},
+						Handler:     respond("set"),
+					},
+					{Trigger: "show", Description: "Show your settings", Handler: respond("show")},
+				},
+			},
+		},
+	}
+
+	for name, tc := range map[string]struct {
+		fields   []string
+		expected string
+	}{
+		"root handler":              {fields: []string{"world"}, expected: "hello:world"},
+		"root handler without args": {fields: nil, expected: "hello:"},
+		"nested handler":            {fields: []string{"settings", "set", "a", "b"}, expected: "set:a,b"},
+		"case insensitive trigger":  {fields: []string{"Settings", "SHOW"}, expected: "show:"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			response, err := root.execute(&Handler{}, &model.CommandArgs{T: testTranslations("en")}, nil, tc.fields)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, response.Text)
+		})
+	}
+
+	t.Run("case insensitive top-level trigger", func(t *testing.T) {
+		handler := &Handler{definitions: []*Definition{root}}
+		response, err := handler.dispatch(&model.CommandArgs{Command: "/HELLO Settings show", T: testTranslations("en")})
+		require.NoError(t, err)
+		assert.Equal(t, "show:", response.Text)
+		assert.Same(t, root.Subcommands[0], find(handler.definitions, []string{"Hello", "settings"}))
+	})
+
+	t.Run("partial input returns usage", func(t *testing.T) {
+		response, err := root.execute(&Handler{}, &model.CommandArgs{T: testTranslations("en")}, nil, []string{"settings"})
+		require.NoError(t, err)
+		assert.Equal(t, model.CommandResponseTypeEphemeral, response.ResponseType)
+		assert.Equal(t, "Usage: `/hello settings <subcommand>`\nManage your settings\n\n"+
+			"Available subcommands:\n- `set <key> <value>`: Change a setting\n- `show`: Show your settings", response.Text)
+	})
+
+	t.Run("unknown subcommand returns usage", func(t *testing.T) {
+		response, err := root.execute(&Handler{}, &model.CommandArgs{T: testTranslations("en")}, nil, []string{"settings", "reset"})
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(response.Text, "Unknown subcommand: reset\n\nUsage: `/hello settings <subcommand>`"))
+	})
+}
@@ -0,0 +1,198 @@
+package command
+
+import (
+	"fmt"
+	"net/mail"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/shared/i18n"
+	"github.com/pkg/errors"
+)
+
+// dialogPath is the plugin-relative path receiving interactive dialog submissions.
+const dialogPath = "api/v1/dialog"
+
+// callbackIDSeparator separates the dialog ID from its state in the callback ID.
+const callbackIDSeparator = ":"
+
+// DialogSubmission is a submitted interactive dialog.
+type DialogSubmission struct {
+	// Request is the submission as sent by the server. The user ID is the authenticated user.
+	Request *model.SubmitDialogRequest
+
+	// State is the state passed when the dialog was opened. It round trips through the client,
+	// so it must not be trusted for authorization.
+	State string
+}
+
+// Dialog describes an interactive dialog opened by a command, and handles its submissions.
+type Dialog struct {
+	// ID identifies the dialog. It must be unique across all definitions, and must not contain
+	// a colon.
+	ID string
+
+	// Dialog is shown to the user when the dialog is opened. Its elements are also used to
+	// validate submissions, as the client side checks can be bypassed.
+	Dialog model.Dialog
+
+	// Validate, if set, checks the submission once its elements are valid. It returns an error
+	// message per invalid field name.
+	Validate func(submission *DialogSubmission) map[string]string
+
+	// Submit handles a valid submission. A nil response closes the dialog.
+	Submit func(submission *DialogSubmission) (*model.SubmitDialogResponse, error)
+
+	// Cancel, if set, is notified when the user cancels the dialog.
+	Cancel func(submission *DialogSubmission)
+}
+
+// DialogSubmitRequest builds the request sent by the server when a user submits the dialog
+// with the given ID. It is meant for simulating submissions in tests.
+func DialogSubmitRequest(dialogID, state, userID string, submission map[string]any) *model.SubmitDialogRequest {
+	return &model.SubmitDialogRequest{
+		Type:       "dialog_submission",
+		CallbackId: dialogID + callbackIDSeparator + state,
+		UserId:     userID,
+		Submission: submission,
+	}
+}
+
+// openDialog opens the dialog with the given ID in response to the command. The state is carried
+// in the callback ID and passed back with the submission. Element defaults may be overridden by
+// name.
+func (c *Handler) openDialog(args *model.CommandArgs, dialogID, state string, defaults map[string]string) error {
+	dialog, ok := c.dialogs[dialogID]
+	if !ok {
+		return errors.Errorf("unknown dialog %q", dialogID)
+	}
+
+	form := dialog.Dialog
+	form.CallbackId = dialogID + callbackIDSeparator + state
+	form.Elements = slices.Clone(form.Elements)
+	if dialog.Cancel != nil {
+		form.NotifyOnCancel = true
+	}
+	for i := range form.Elements {
+		if value, ok := defaults[form.Elements[i].Name]; ok {
+			form.Elements[i].Default = value
+		}
+	}
+
+	err := c.client.Frontend.OpenInteractiveDialog(model.OpenDialogRequest{
+		TriggerId: args.TriggerId,
+		URL:       fmt.Sprintf("/plugins/%s/%s", c.pluginID, dialogPath),
+		Dialog:    form,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to open interactive dialog")
+	}
+
+	return nil
+}
+
+// SubmitDialog routes a dialog submission to the dialog it was opened from, returning the
+// per-field errors if the submission is invalid.
+func (c *Handler) SubmitDialog(request *model.SubmitDialogRequest) (*model.SubmitDialogResponse, error) {
+	dialogID, state, _ := strings.Cut(request.CallbackId, callbackIDSeparator)
+	dialog, ok := c.dialogs[dialogID]
+	if !ok {
+		return nil, errors.Errorf("unknown dialog %q", dialogID)
+	}
+
+	submission := &DialogSubmission{
+		Request: request,
+		State:   state,
+	}
+
+	if request.Cancelled {
+		if dialog.Cancel != nil {
+			dialog.Cancel(submission)
+		}
+		return nil, nil
+	}
+
+	if errs := validateElements(c.userTranslations(request.UserId), dialog.Dialog.Elements, request.Submission); len(errs) > 0 {
+		return &model.SubmitDialogResponse{Errors: errs}, nil
+	}
+	if dialog.Validate != nil {
+		if errs := dialog.Validate(submission); len(errs) > 0 {
+			return &model.SubmitDialogResponse{Errors: errs}, nil
+		}
+	}
+
+	return dialog.Submit(submission)
+}
+
+// indexDialogs collects the dialogs of the definitions and their subcommands by ID.
+func indexDialogs(dialogs map[string]*Dialog, definitions []*Definition) {
+	for _, definition := range definitions {
+		for _, dialog := range definition.Dialogs {
+			dialogs[dialog.ID] = dialog
+		}
+		indexDialogs(dialogs, definition.Subcommands)
+	}
+}
+
+// validateElements checks the submitted values against the constraints of the dialog elements,
+// returning an error message per invalid field name, translated with T.
+func validateElements(T i18n.TranslateFunc, elements []model.DialogElement, submission map[string]any) map[string]string {
+	errs := map[string]string{}
+
+	for _, element := range elements {
+		value, ok := submission[element.Name]
+		if !ok || value == nil || value == "" {
+			if !element.Optional && element.Type != "bool" {
+				errs[element.Name] = T("command.dialog.required")
+			}
+			continue
+		}
+
+		if element.Type == "bool" {
+			if _, ok := value.(bool); !ok {
+				errs[element.Name] = T("command.dialog.bool")
+			}
+			continue
+		}
+
+		if _, ok := value.(float64); ok && element.SubType == "number" {
+			continue
+		}
+
+		text, ok := value.(string)
+		if !ok {
+			errs[element.Name] = T("command.dialog.invalid")
+			continue
+		}
+
+		switch element.Type {
+		case "text", "textarea":
+			length := utf8.RuneCountInString(text)
+			switch {
+			case element.MinLength > 0 && length < element.MinLength:
+				errs[element.Name] = T("command.dialog.min_length", map[string]any{"Count": element.MinLength})
+			case element.MaxLength > 0 && length > element.MaxLength:
+				errs[element.Name] = T("command.dialog.max_length", map[string]any{"Count": element.MaxLength})
+			case element.SubType == "number":
+				if _, err := strconv.ParseFloat(text, 64); err != nil {
+					errs[element.Name] = T("command.dialog.number")
+				}
+			case element.SubType == "email":
+				if _, err := mail.ParseAddress(text); err != nil {
+					errs[element.Name] = T("command.dialog.email")
+				}
+			}
+		case "select", "radio":
+			if element.DataSource == "" && !element.MultiSelect && !slices.ContainsFunc(element.Options, func(option *model.PostActionOptions) bool {
+				return option.Value == text
+			}) {
+				errs[element.Name] = T("command.dialog.option")
+			}
+		}
+	}
+
+	return errs
+}
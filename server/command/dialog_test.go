@@ -0,0 +1,117 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// submitDialog simulates a user submitting the dialog with the given ID.
+func submitDialog(t *testing.T, handler *Handler, dialogID, state string, submission map[string]any) *model.SubmitDialogResponse {
+	t.Helper()
+
+	response, err := handler.SubmitDialog(DialogSubmitRequest(dialogID, state, "user-id", submission))
+	require.NoError(t, err)
+	return response
+}
+
+func TestDialogs(t *testing.T) {
+	env := setupTest()
+
+	var submitted *DialogSubmission
+	var cancelled bool
+	definition := &Definition{
+		Trigger: "hello",
+		Dialogs: []*Dialog{
+			{
+				ID: "greeting",
+				Dialog: model.Dialog{
+					Title: "Greeting",
+					Elements: []model.DialogElement{
+						{Name: "message", Type: "text", MinLength: 2, MaxLength: 10},
+						{Name: "count", Type: "text", SubType: "number", Optional: true},
+						{Name: "tone", Type: "select", Options: []*model.PostActionOptions{{Text: "Warm", Value: "warm"}}},
+					},
+				},
+				Validate: func(submission *DialogSubmission) map[string]string {
+					if submission.Request.Submission["message"] == "bye" {
+						return map[string]string{"message": "Say hello instead."}
+					}
+					return nil
+				},
+				Submit: func(submission *DialogSubmission) (*model.SubmitDialogResponse, error) {
+					submitted = submission
+					return nil, nil
+				},
+				Cancel: func(submission *DialogSubmission) {
+					cancelled = true
+				},
+			},
+		},
+	}
+
+	handler := &Handler{client: env.client, pluginID: "plugin-id", dialogs: map[string]*Dialog{}, translations: testTranslations}
+	indexDialogs(handler.dialogs, []*Definition{definition})
+
+	t.Run("open", func(t *testing.T) {
+		env.api.On("OpenInteractiveDialog", mock.MatchedBy(func(request model.OpenDialogRequest) bool {
+			return request.TriggerId == "trigger-id" &&
+				request.URL == "/plugins/plugin-id/api/v1/dialog" &&
+				request.Dialog.CallbackId == "greeting:channel-id" &&
+				request.Dialog.NotifyOnCancel &&
+				request.Dialog.Elements[0].Default == "hi"
+		})).Return(nil).Once()
+
+		err := handler.openDialog(&model.CommandArgs{TriggerId: "trigger-id"}, "greeting", "channel-id", map[string]string{"message": "hi"})
+		require.NoError(t, err)
+		assert.Empty(t, definition.Dialogs[0].Dialog.Elements[0].Default)
+		env.api.AssertExpectations(t)
+	})
+
+	t.Run("element validation", func(t *testing.T) {
+		env.api.On("GetUser", "user-id").Return(&model.User{Id: "user-id", Locale: "en"}, nil)
+
+		response := submitDialog(t, handler, "greeting", "", map[string]any{"message": "a", "count": "many", "tone": "cold"})
+		assert.Equal(t, map[string]string{
+			"message": "Must be at least 2 characters.",
+			"count":   "Must be a number.",
+			"tone":    "Must be one of the listed options.",
+		}, response.Errors)
+
+		response = submitDialog(t, handler, "greeting", "", map[string]any{})
+		assert.Equal(t, map[string]string{
+			"message": "This field is required.",
+			"tone":    "This field is required.",
+		}, response.Errors)
+	})
+
+	t.Run("custom validation", func(t *testing.T) {
+		response := submitDialog(t, handler, "greeting", "", map[string]any{"message": "bye", "tone": "warm"})
+		assert.Equal(t, map[string]string{"message": "Say hello instead."}, response.Errors)
+	})
+
+	t.Run("submit", func(t *testing.T) {
+		response := submitDialog(t, handler, "greeting", "channel-id", map[string]any{"message": "hello", "count": float64(2), "tone": "warm"})
+		assert.Nil(t, response)
+		require.NotNil(t, submitted)
+		assert.Equal(t, "channel-id", submitted.State)
+		assert.Equal(t, "user-id", submitted.Request.UserId)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		request := DialogSubmitRequest("greeting", "", "user-id", nil)
+		request.Cancelled = true
+		response, err := handler.SubmitDialog(request)
+		require.NoError(t, err)
+		assert.Nil(t, response)
+		assert.True(t, cancelled)
+	})
+
+	t.Run("unknown dialog", func(t *testing.T) {
+		_, err := handler.SubmitDialog(DialogSubmitRequest("unknown", "", "user-id", nil))
+		assert.Error(t, err)
+	})
+}
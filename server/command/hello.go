@@ -0,0 +1,170 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/shared/i18n"
+	"github.com/pkg/errors"
+)
+
+// helloTargets are the resolved recipients of a greeting.
+type helloTargets struct {
+	users    []*model.User
+	channels []*model.Channel
+
+	// skipped holds the usernames of deactivated users, which are not greeted.
+	skipped []string
+}
+
+// names returns the @usernames and ~channels being greeted.
+func (t *helloTargets) names() []string {
+	names := make([]string, 0, len(t.users)+len(t.channels))
+	for _, user := range t.users {
+		names = append(names, "@"+user.Username)
+	}
+	for _, channel := range t.channels {
+		names = append(names, "~"+channel.Name)
+	}
+	return names
+}
+
+func (c *Handler) executeHelloCommand(args *model.CommandArgs, values *Values) (*model.CommandResponse, error) {
+	T := translate(args)
+	targets, problems := c.resolveHelloTargets(args, values.Strings("targets"))
+	if len(problems) > 0 {
+		return &model.CommandResponse{
+			ResponseType: model.CommandResponseTypeEphemeral,
+			Text:         strings.Join(problems, "\n"),
+		}, nil
+	}
+
+	var notes []string
+	if len(targets.skipped) > 0 {
+		notes = append(notes, T("command.hello.skipped", map[string]any{"Names": joinNames(T, targets.skipped)}))
+	}
+
+	if len(targets.users) == 0 && len(targets.channels) == 0 {
+		return &model.CommandResponse{
+			ResponseType: model.CommandResponseTypeEphemeral,
+			Text:         strings.Join(append(notes, T("command.hello.nobody")), "\n"),
+		}, nil
+	}
+
+	g := c.newGreeter(args)
+
+	if values.Bool("dm") {
+		sent, failed, err := c.sendHelloFromBot(args, targets, g)
+		if err != nil {
+			return nil, err
+		}
+		if len(sent) > 0 {
+			notes = append(notes, T("command.hello.sent", map[string]any{"Names": joinNames(T, sent)}))
+		}
+		if len(failed) > 0 {
+			notes = append(notes, T("command.hello.failed", map[string]any{"Names": joinNames(T, failed)}))
+		}
+		return &model.CommandResponse{
+			ResponseType: model.CommandResponseTypeEphemeral,
+			Text:         strings.Join(notes, "\n"),
+		}, nil
+	}
+
+	if len(notes) > 0 {
+		c.client.Post.SendEphemeralPost(args.UserId, &model.Post{
+			ChannelId: args.ChannelId,
+			RootId:    args.RootId,
+			Message:   strings.Join(notes, "\n"),
+		})
+	}
+
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeInChannel,
+		Text:         g.greet(joinNames(T, targets.names()), T("command.hello.greeting", map[string]any{"Names": joinNames(T, targets.names())})),
+	}, nil
+}
+
+// resolveHelloTargets looks up the users and channels to greet. Channels the invoking user
+// cannot read are reported as not found, so that their existence is not disclosed.
+func (c *Handler) resolveHelloTargets(args *model.CommandArgs, names []string) (*helloTargets, []string) {
+	T := translate(args)
+	targets := &helloTargets{}
+	var problems []string
+
+	for _, name := range names {
+		if channelName, ok := strings.CutPrefix(name, "~"); ok {
+			channel, err := c.client.Channel.GetByName(args.TeamId, channelName, false)
+			if err != nil || !c.client.User.HasPermissionToChannel(args.UserId, channel.Id, model.PermissionReadChannelContent) {
+				problems = append(problems, T("command.hello.channel_not_found", map[string]any{"Channel": channelName}))
+				continue
+			}
+			targets.channels = append(targets.channels, channel)
+			continue
+		}
+
+		username := strings.TrimPrefix(name, "@")
+		user, err := c.client.User.GetByUsername(username)
+		if err != nil {
+			problems = append(problems, T("command.hello.user_not_found", map[string]any{"Username": username}))
+			continue
+		}
+		if user.DeleteAt != 0 {
+			targets.skipped = append(targets.skipped, "@"+user.Username)
+			continue
+		}
+		targets.users = append(targets.users, user)
+	}
+
+	return targets, problems
+}
+
+// sendHelloFromBot greets each user with a direct message and each channel with a post from the
+// plugin's bot, rendered with their greeter. It returns the names of the targets that were and
+// were not greeted.
+func (c *Handler) sendHelloFromBot(args *model.CommandArgs, targets *helloTargets, g *greeter) (sent, failed []string, err error) {
+	sender, err := c.client.User.Get(args.UserId)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get sender")
+	}
+	T := translate(args)
+
+	for _, user := range targets.users {
+		post := &model.Post{
+			Message: g.greet("@"+user.Username, T("command.hello.dm_greeting", map[string]any{"Sender": sender.Username, "Recipient": user.Username})),
+		}
+		if err := c.client.Post.DM(c.botUserID, user.Id, post); err != nil {
+			c.client.Log.Warn("Failed to send greeting", "user_id", user.Id, "error", err)
+			failed = append(failed, "@"+user.Username)
+			continue
+		}
+		sent = append(sent, "@"+user.Username)
+	}
+
+	for _, channel := range targets.channels {
+		if !c.client.User.HasPermissionToChannel(args.UserId, channel.Id, model.PermissionCreatePost) {
+			failed = append(failed, "~"+channel.Name)
+			continue
+		}
+		post := &model.Post{
+			UserId:    c.botUserID,
+			ChannelId: channel.Id,
+			Message:   g.greet("~"+channel.Name, T("command.hello.channel_greeting", map[string]any{"Sender": sender.Username, "Channel": channel.Name})),
+		}
+		if err := c.client.Post.CreatePost(post); err != nil {
+			c.client.Log.Warn("Failed to send greeting", "channel_id", channel.Id, "error", err)
+			failed = append(failed, "~"+channel.Name)
+			continue
+		}
+		sent = append(sent, "~"+channel.Name)
+	}
+
+	return sent, failed, nil
+}
+
+// joinNames joins names into a human readable list, e.g. `@alice, @bob and ~town-square`.
+func joinNames(T i18n.TranslateFunc, names []string) string {
+	if len(names) <= 1 {
+		return strings.Join(names, "")
+	}
+	return T("command.hello.names", map[string]any{"Names": strings.Join(names[:len(names)-1], ", "), "Last": names[len(names)-1]})
+}
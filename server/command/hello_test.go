@@ -0,0 +1,103 @@
+package command
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-starter-template/server/store/kvstore"
+)
+
+func TestExecuteHelloCommand(t *testing.T) {
+	alice := &model.User{Id: "alice-id", Username: "alice"}
+	bob := &model.User{Id: "bob-id", Username: "bob"}
+	carol := &model.User{Id: "carol-id", Username: "carol", DeleteAt: 1}
+	townSquare := &model.Channel{Id: "town-square-id", Name: "town-square"}
+	notFound := model.NewAppError("Get", "app.missing", nil, "", http.StatusNotFound)
+
+	setup := func() (*env, *Handler, *model.CommandArgs) {
+		env := setupTest()
+		env.api.On("GetUserByUsername", "alice").Return(alice, nil)
+		env.api.On("GetUserByUsername", "bob").Return(bob, nil)
+		env.api.On("GetUserByUsername", "carol").Return(carol, nil)
+		env.api.On("GetUserByUsername", "nobody").Return(nil, notFound)
+		env.api.On("GetChannelByName", "team-id", "town-square", false).Return(townSquare, nil)
+		env.api.On("GetChannelByName", "team-id", "secret", false).Return(&model.Channel{Id: "secret-id", Name: "secret"}, nil)
+		env.api.On("HasPermissionToChannel", "sender-id", "town-square-id", model.PermissionReadChannelContent).Return(true)
+		env.api.On("HasPermissionToChannel", "sender-id", "secret-id", model.PermissionReadChannelContent).Return(false)
+		env.api.On("KVGet", "template_key-sender-id").Return(nil, nil)
+
+		handler := &Handler{client: env.client, kvstore: kvstore.NewKVStore(env.client), botUserID: "bot-id"}
+		args := &model.CommandArgs{UserId: "sender-id", TeamId: "team-id", ChannelId: "channel-id", T: testTranslations("en")}
+		return env, handler, args
+	}
+
+	execute := func(handler *Handler, args *model.CommandArgs, fields ...string) *model.CommandResponse {
+		values := &Values{Fields: fields, values: map[string]any{}}
+		var targets []any
+		for _, field := range fields {
+			if field == "--dm" {
+				values.values["dm"] = true
+				continue
+			}
+			targets = append(targets, field)
+		}
+		values.values["targets"] = targets
+
+		response, err := handler.executeHelloCommand(args, values)
+		require.NoError(t, err)
+		return response
+	}
+
+	t.Run("greets users and channels in channel", func(t *testing.T) {
+		_, handler, args := setup()
+		response := execute(handler, args, "@alice", "bob", "~town-square")
+		assert.Equal(t, model.CommandResponseTypeInChannel, response.ResponseType)
+		assert.Equal(t, "Hello, @alice, @bob and ~town-square", response.Text)
+	})
+
+	t.Run("rejects unknown users and unreadable channels", func(t *testing.T) {
+		_, handler, args := setup()
+		response := execute(handler, args, "@alice", "@nobody", "~secret")
+		assert.Equal(t, model.CommandResponseTypeEphemeral, response.ResponseType)
+		assert.Equal(t, "User @nobody not found.\nChannel ~secret not found.", response.Text)
+	})
+
+	t.Run("skips deactivated users", func(t *testing.T) {
+		env, handler, args := setup()
+		env.api.On("SendEphemeralPost", "sender-id", &model.Post{
+			ChannelId: "channel-id",
+			Message:   "Skipped deactivated users: @carol.",
+		}).Return(&model.Post{}).Once()
+
+		response := execute(handler, args, "@alice", "@carol")
+		assert.Equal(t, "Hello, @alice", response.Text)
+		env.api.AssertNumberOfCalls(t, "SendEphemeralPost", 1)
+
+		response = execute(handler, args, "@carol")
+		assert.Equal(t, model.CommandResponseTypeEphemeral, response.ResponseType)
+		assert.Equal(t, "Skipped deactivated users: @carol.\nThere is nobody to say hello to.", response.Text)
+	})
+
+	t.Run("sends greetings from the bot", func(t *testing.T) {
+		env, handler, args := setup()
+		env.api.On("GetUser", "sender-id").Return(&model.User{Id: "sender-id", Username: "sender"}, nil)
+		env.api.On("GetDirectChannel", "bot-id", "alice-id").Return(&model.Channel{Id: "dm-id"}, nil)
+		env.api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "dm-id" && post.UserId == "bot-id" && post.Message == "@sender says hello, @alice!"
+		})).Return(&model.Post{}, nil).Once()
+		env.api.On("HasPermissionToChannel", "sender-id", "town-square-id", model.PermissionCreatePost).Return(true)
+		env.api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "town-square-id" && post.UserId == "bot-id" && post.Message == "@sender says hello, ~town-square!"
+		})).Return(&model.Post{}, nil).Once()
+
+		response := execute(handler, args, "@alice", "~town-square", "--dm")
+		assert.Equal(t, model.CommandResponseTypeEphemeral, response.ResponseType)
+		assert.Equal(t, "Said hello to @alice and ~town-square.", response.Text)
+		env.api.AssertNumberOfCalls(t, "CreatePost", 2)
+	})
+}
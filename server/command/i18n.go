@@ -0,0 +1,57 @@
+package command
+
+import (
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/shared/i18n"
+)
+
+// translationsFor returns the function translating messages into the locale of the user, or
+// into English if the user is nil. Messages are not translated if the handler has no
+// translations.
+func (c *Handler) translationsFor(user *model.User) i18n.TranslateFunc {
+	if c.translations == nil {
+		return i18n.IdentityTfunc()
+	}
+
+	locale := ""
+	if user != nil {
+		locale = user.Locale
+	}
+	return c.translations(locale)
+}
+
+// userTranslations returns the function translating messages into the locale of the user with
+// the given ID, falling back to English if the user cannot be loaded.
+func (c *Handler) userTranslations(userID string) i18n.TranslateFunc {
+	if c.translations == nil {
+		return i18n.IdentityTfunc()
+	}
+
+	user, err := c.client.User.Get(userID)
+	if err != nil {
+		c.client.Log.Warn("Failed to get user locale", "user_id", userID, "error", err)
+		user = nil
+	}
+	return c.translationsFor(user)
+}
+
+// localize returns a copy of the args translating messages into the locale of the invoking
+// user, unless they already do.
+func (c *Handler) localize(args *model.CommandArgs) *model.CommandArgs {
+	if args.T != nil {
+		return args
+	}
+
+	localized := *args
+	localized.T = c.userTranslations(args.UserId)
+	return &localized
+}
+
+// translate returns the function translating the replies to the invoking user, as set by
+// Handle. Messages are not translated if the args were not localized.
+func translate(args *model.CommandArgs) i18n.TranslateFunc {
+	if args.T == nil {
+		return i18n.IdentityTfunc()
+	}
+	return args.T
+}
@@ -0,0 +1,56 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/shared/i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalize(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "en.json"), []byte(`[
+		{"id": "command.unknown", "translation": "Unknown command: {{.Command}}"},
+		{"id": "command.empty", "translation": "Empty command"}
+	]`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "fr.json"), []byte(`[
+		{"id": "command.unknown", "translation": "Commande inconnue : {{.Command}}"}
+	]`), 0600))
+	translations, err := i18n.GetTranslationFuncForDir(dir)
+	require.NoError(t, err)
+
+	env := setupTest()
+	handler := &Handler{client: env.client, translations: translations}
+	env.api.On("GetUser", "french-id").Return(&model.User{Id: "french-id", Locale: "fr"}, nil)
+	env.api.On("GetUser", "german-id").Return(&model.User{Id: "german-id", Locale: "de"}, nil)
+	env.api.On("GetUser", "missing-id").Return(nil, &model.AppError{Message: "not found"})
+	env.api.On("LogWarn", "Failed to get user locale", "user_id", "missing-id", "error", mock.Anything).Return()
+
+	for name, tc := range map[string]struct {
+		userID   string
+		command  string
+		expected string
+	}{
+		"user locale":                      {userID: "french-id", command: "/unknown", expected: "Commande inconnue : /unknown"},
+		"missing translations use English": {userID: "french-id", command: "", expected: "Empty command"},
+		"unavailable locale uses English":  {userID: "german-id", command: "/unknown", expected: "Unknown command: /unknown"},
+		"unknown user uses English":        {userID: "missing-id", command: "/unknown", expected: "Unknown command: /unknown"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			response, err := handler.Handle(&model.CommandArgs{UserId: tc.userID, Command: tc.command})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, response.Text)
+		})
+	}
+
+	t.Run("args translations take precedence", func(t *testing.T) {
+		response, err := handler.Handle(&model.CommandArgs{UserId: "french-id", Command: "/unknown", T: translations("en")})
+		require.NoError(t, err)
+		assert.Equal(t, "Unknown command: /unknown", response.Text)
+	})
+}
@@ -0,0 +1,138 @@
+package command
+
+import (
+	"fmt"
+	"math"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+)
+
+// HandleFunc executes a slash command invocation, as Command.Handle does.
+type HandleFunc func(args *model.CommandArgs) (*model.CommandResponse, error)
+
+// Middleware wraps the execution of every slash command invocation, e.g. to log, authorize or
+// measure it. Middleware registered first runs outermost.
+type Middleware func(next HandleFunc) HandleFunc
+
+// Outcome is how a slash command invocation ended.
+type Outcome string
+
+const (
+	// OutcomeSuccess is the outcome of the invocations answered without an error, including
+	// the ones answering with usage or validation messages.
+	OutcomeSuccess Outcome = "success"
+
+	// OutcomeError is the outcome of the invocations failing with an error.
+	OutcomeError Outcome = "error"
+
+	// OutcomePanic is the outcome of the invocations whose handler panicked.
+	OutcomePanic Outcome = "panic"
+
+	// OutcomeRateLimited is the outcome of the invocations rejected by the rate limits.
+	OutcomeRateLimited Outcome = "rate_limited"
+)
+
+// ObserveFunc is notified of every slash command invocation once it completes.
+type ObserveFunc func(args *model.CommandArgs, trigger string, duration time.Duration, outcome Outcome)
+
+// trigger returns the trigger of the invoked command, e.g. `hello`.
+func trigger(args *model.CommandArgs) string {
+	command, _, _ := strings.Cut(strings.TrimSpace(args.Command), " ")
+	return strings.TrimPrefix(command, "/")
+}
+
+// LoggingMiddleware logs who ran which command, where and how long it took.
+func LoggingMiddleware(log pluginapi.LogService) Middleware {
+	return func(next HandleFunc) HandleFunc {
+		return func(args *model.CommandArgs) (*model.CommandResponse, error) {
+			start := time.Now()
+			response, err := next(args)
+
+			keyValuePairs := []any{
+				"trigger", trigger(args),
+				"user_id", args.UserId,
+				"channel_id", args.ChannelId,
+				"team_id", args.TeamId,
+				"duration_ms", time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				log.Warn("Slash command failed", append(keyValuePairs, "error", err)...)
+			} else {
+				log.Info("Slash command executed", keyValuePairs...)
+			}
+
+			return response, err
+		}
+	}
+}
+
+// RecoveryMiddleware recovers from panics in command handlers. The panic is logged with its
+// stack trace and a correlation ID, which is also shown to the user so that administrators can
+// find the matching log entry.
+func RecoveryMiddleware(log pluginapi.LogService) Middleware {
+	return func(next HandleFunc) HandleFunc {
+		return func(args *model.CommandArgs) (response *model.CommandResponse, err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					correlationID := model.NewId()
+					log.Error("Recovered from a panic in a slash command",
+						"correlation_id", correlationID,
+						"trigger", trigger(args),
+						"user_id", args.UserId,
+						"panic", fmt.Sprint(r),
+						"stack", string(debug.Stack()),
+					)
+
+					response = &model.CommandResponse{
+						ResponseType: model.CommandResponseTypeEphemeral,
+						Text:         translate(args)("command.recovered", map[string]any{"ErrorID": correlationID}),
+					}
+					err = nil
+				}
+			}()
+
+			return next(args)
+		}
+	}
+}
+
+// RateLimiter limits the rate of the invocations of every user by name, e.g. a
+// ratelimit.Limiter.
+type RateLimiter interface {
+	// Allow returns whether the user may run the command limited by the first of the names
+	// having a limit, or how long to wait.
+	Allow(userID string, names ...string) (bool, time.Duration)
+}
+
+// rateLimitMiddleware rejects the invocations of users running a command too often with an
+// ephemeral message, calling rejected. Limits are named after the declared triggers of the
+// commands among the definitions, e.g. `/hello schedule` even if `hello` is configured with
+// another trigger, and the most specific limit applies.
+func rateLimitMiddleware(limiter RateLimiter, definitions []*Definition, rejected func()) Middleware {
+	return func(next HandleFunc) HandleFunc {
+		return func(args *model.CommandArgs) (*model.CommandResponse, error) {
+			path, _ := declaredPath(definitions, strings.Fields(args.Command))
+			if path == nil {
+				path = []string{strings.ToLower(trigger(args))}
+			}
+			names := make([]string, 0, len(path))
+			for depth := len(path); depth > 0; depth-- {
+				names = append(names, "/"+strings.Join(path[:depth], " "))
+			}
+
+			if allowed, retryAfter := limiter.Allow(args.UserId, names...); !allowed {
+				rejected()
+				return &model.CommandResponse{
+					ResponseType: model.CommandResponseTypeEphemeral,
+					Text:         translate(args)("command.rate_limited", map[string]any{"Seconds": max(1, int(math.Ceil(retryAfter.Seconds())))}),
+				}, nil
+			}
+
+			return next(args)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package command
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	args := &model.CommandArgs{Command: "/hello world", UserId: "user-id", ChannelId: "channel-id", TeamId: "team-id", T: testTranslations("en")}
+
+	t.Run("runs in registration order", func(t *testing.T) {
+		var calls []string
+		record := func(name string) Middleware {
+			return func(next HandleFunc) HandleFunc {
+				return func(args *model.CommandArgs) (*model.CommandResponse, error) {
+					calls = append(calls, name)
+					return next(args)
+				}
+			}
+		}
+
+		handler := &Handler{}
+		handler.Use(record("first"), record("second"))
+		_, err := handler.Handle(&model.CommandArgs{Command: "/unknown"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"first", "second"}, calls)
+	})
+
+	t.Run("logging", func(t *testing.T) {
+		env := setupTest()
+		env.api.On("LogWarn", "Slash command failed", "trigger", "hello", "user_id", "user-id", "channel_id", "channel-id",
+			"team_id", "team-id", "duration_ms", mock.AnythingOfType("int64"), "error", mock.Anything).Return().Once()
+
+		_, err := LoggingMiddleware(env.client.Log)(func(*model.CommandArgs) (*model.CommandResponse, error) {
+			return nil, errors.New("boom")
+		})(args)
+		assert.Error(t, err)
+		env.api.AssertExpectations(t)
+	})
+
+	t.Run("recovery", func(t *testing.T) {
+		env := setupTest()
+		var correlationID string
+		env.api.On("LogError", "Recovered from a panic in a slash command", "correlation_id", mock.Anything, "trigger", "hello",
+			"user_id", "user-id", "panic", "boom", "stack", mock.AnythingOfType("string")).Run(func(args mock.Arguments) {
+			correlationID = args.String(2)
+		}).Return().Once()
+
+		response, err := RecoveryMiddleware(env.client.Log)(func(*model.CommandArgs) (*model.CommandResponse, error) {
+			panic("boom")
+		})(args)
+		require.NoError(t, err)
+		env.api.AssertExpectations(t)
+		assert.Equal(t, model.CommandResponseTypeEphemeral, response.ResponseType)
+		assert.Contains(t, response.Text, correlationID)
+	})
+
+	t.Run("rate limit", func(t *testing.T) {
+		limiter := rateLimiterFunc(func(userID string, names ...string) (bool, time.Duration) {
+			assert.Equal(t, "user-id", userID)
+			assert.Equal(t, []string{"/hello schedule", "/hello"}, names)
+			return false, 1500 * time.Millisecond
+		})
+		hello := &Definition{Trigger: "hello", Subcommands: []*Definition{{Trigger: "schedule"}}}
+		definitions := []*Definition{configureDefinition(hello, Config{}, "hi", nil, nil)}
+		var rejected bool
+		response, err := rateLimitMiddleware(limiter, definitions, func() { rejected = true })(func(*model.CommandArgs) (*model.CommandResponse, error) {
+			t.Fatal("the command should not run")
+			return nil, nil
+		})(&model.CommandArgs{Command: "/hi Schedule in 2h @alice", UserId: "user-id", T: testTranslations("en")})
+		require.NoError(t, err)
+		assert.True(t, rejected)
+		assert.Equal(t, model.CommandResponseTypeEphemeral, response.ResponseType)
+		assert.Equal(t, "You are running this command too often. Please try again in 2 seconds.", response.Text)
+	})
+}
+
+func TestObserve(t *testing.T) {
+	for name, tc := range map[string]struct {
+		handler  func(args *model.CommandArgs, values *Values) (*model.CommandResponse, error)
+		allowed  bool
+		expected Outcome
+	}{
+		"success": {
+			handler: func(*model.CommandArgs, *Values) (*model.CommandResponse, error) {
+				return &model.CommandResponse{}, nil
+			},
+			allowed:  true,
+			expected: OutcomeSuccess,
+		},
+		"error": {
+			handler:  func(*model.CommandArgs, *Values) (*model.CommandResponse, error) { return nil, errors.New("boom") },
+			allowed:  true,
+			expected: OutcomeError,
+		},
+		"panic": {
+			handler:  func(*model.CommandArgs, *Values) (*model.CommandResponse, error) { panic("boom") },
+			allowed:  true,
+			expected: OutcomePanic,
+		},
+		"rate limited": {
+			handler: func(*model.CommandArgs, *Values) (*model.CommandResponse, error) {
+				t.Fatal("the command should not run")
+				return nil, nil
+			},
+			expected: OutcomeRateLimited,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			env := setupTest()
+			env.api.On("LogError", "Recovered from a panic in a slash command", mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+				mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
+
+			handler := &Handler{client: env.client}
+			handler.definitions = []*Definition{{Trigger: "hello", Handler: tc.handler}}
+			handler.Use(RecoveryMiddleware(env.client.Log))
+			handler.LimitRate(rateLimiterFunc(func(string, ...string) (bool, time.Duration) { return tc.allowed, time.Second }))
+			var observed []Outcome
+			handler.Observe(func(args *model.CommandArgs, trigger string, duration time.Duration, outcome Outcome) {
+				assert.Equal(t, "hello", trigger)
+				observed = append(observed, outcome)
+			})
+
+			_, _ = handler.Handle(&model.CommandArgs{Command: "/hello", UserId: "user-id", T: testTranslations("en")})
+			assert.Equal(t, []Outcome{tc.expected}, observed)
+		})
+	}
+}
+
+// rateLimiterFunc limits the rate of the invocations with a function.
+type rateLimiterFunc func(userID string, names ...string) (bool, time.Duration)
+
+func (f rateLimiterFunc) Allow(userID string, names ...string) (bool, time.Duration) {
+	return f(userID, names...)
+}
@@ -11,7 +11,9 @@ package mocks
 
 import (
 	reflect "reflect"
+	time "time"
 
+	command "github.com/mattermost/mattermost-plugin-starter-template/server/command"
 	model "github.com/mattermost/mattermost/server/public/model"
 	gomock "go.uber.org/mock/gomock"
 )
@@ -40,6 +42,32 @@ func (m *MockCommand) EXPECT() *MockCommandMockRecorder {
 	return m.recorder
 }
 
+// Close mocks base method.
+func (m *MockCommand) Close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockCommandMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockCommand)(nil).Close))
+}
+
+// Configure mocks base method.
+func (m *MockCommand) Configure(config command.Config) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Configure", config)
+}
+
+// Configure indicates an expected call of Configure.
+func (mr *MockCommandMockRecorder) Configure(config any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Configure", reflect.TypeOf((*MockCommand)(nil).Configure), config)
+}
+
 // Handle mocks base method.
 func (m *MockCommand) Handle(args *model.CommandArgs) (*model.CommandResponse, error) {
 	m.ctrl.T.Helper()
@@ -55,16 +83,101 @@ func (mr *MockCommandMockRecorder) Handle(args any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Handle", reflect.TypeOf((*MockCommand)(nil).Handle), args)
 }
 
-// executeHelloCommand mocks base method.
-func (m *MockCommand) executeHelloCommand(args *model.CommandArgs) *model.CommandResponse {
+// HandleAction mocks base method.
+func (m *MockCommand) HandleAction(request *model.PostActionIntegrationRequest) (*model.PostActionIntegrationResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "executeHelloCommand", args)
-	ret0, _ := ret[0].(*model.CommandResponse)
+	ret := m.ctrl.Call(m, "HandleAction", request)
+	ret0, _ := ret[0].(*model.PostActionIntegrationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HandleAction indicates an expected call of HandleAction.
+func (mr *MockCommandMockRecorder) HandleAction(request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleAction", reflect.TypeOf((*MockCommand)(nil).HandleAction), request)
+}
+
+// LimitRate mocks base method.
+func (m *MockCommand) LimitRate(limiter command.RateLimiter) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "LimitRate", limiter)
+}
+
+// LimitRate indicates an expected call of LimitRate.
+func (mr *MockCommandMockRecorder) LimitRate(limiter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LimitRate", reflect.TypeOf((*MockCommand)(nil).LimitRate), limiter)
+}
+
+// Observe mocks base method.
+func (m *MockCommand) Observe(observe command.ObserveFunc) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Observe", observe)
+}
+
+// Observe indicates an expected call of Observe.
+func (mr *MockCommandMockRecorder) Observe(observe any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Observe", reflect.TypeOf((*MockCommand)(nil).Observe), observe)
+}
+
+// RunScheduledCommands mocks base method.
+func (m *MockCommand) RunScheduledCommands(now time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunScheduledCommands", now)
+	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// executeHelloCommand indicates an expected call of executeHelloCommand.
-func (mr *MockCommandMockRecorder) executeHelloCommand(args any) *gomock.Call {
+// RunScheduledCommands indicates an expected call of RunScheduledCommands.
+func (mr *MockCommandMockRecorder) RunScheduledCommands(now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunScheduledCommands", reflect.TypeOf((*MockCommand)(nil).RunScheduledCommands), now)
+}
+
+// SubmitDialog mocks base method.
+func (m *MockCommand) SubmitDialog(request *model.SubmitDialogRequest) (*model.SubmitDialogResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubmitDialog", request)
+	ret0, _ := ret[0].(*model.SubmitDialogResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SubmitDialog indicates an expected call of SubmitDialog.
+func (mr *MockCommandMockRecorder) SubmitDialog(request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubmitDialog", reflect.TypeOf((*MockCommand)(nil).SubmitDialog), request)
+}
+
+// Suggest mocks base method.
+func (m *MockCommand) Suggest(args *model.CommandArgs, arg1, argument, userInput string) ([]model.AutocompleteListItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Suggest", args, arg1, argument, userInput)
+	ret0, _ := ret[0].([]model.AutocompleteListItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Suggest indicates an expected call of Suggest.
+func (mr *MockCommandMockRecorder) Suggest(args, arg1, argument, userInput any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Suggest", reflect.TypeOf((*MockCommand)(nil).Suggest), args, arg1, argument, userInput)
+}
+
+// Use mocks base method.
+func (m *MockCommand) Use(middleware ...command.Middleware) {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range middleware {
+		varargs = append(varargs, a)
+	}
+	m.ctrl.Call(m, "Use", varargs...)
+}
+
+// Use indicates an expected call of Use.
+func (mr *MockCommandMockRecorder) Use(middleware ...any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "executeHelloCommand", reflect.TypeOf((*MockCommand)(nil).executeHelloCommand), args)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Use", reflect.TypeOf((*MockCommand)(nil).Use), middleware...)
 }
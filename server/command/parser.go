@@ -0,0 +1,387 @@
+package command
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+// ArgumentType determines how the value of an argument is parsed and validated.
+type ArgumentType int
+
+const (
+	// ArgumentTypeString accepts any text.
+	ArgumentTypeString ArgumentType = iota
+	// ArgumentTypeInt accepts a base 10 integer.
+	ArgumentTypeInt
+	// ArgumentTypeBool accepts true/false, 1/0, t/f. A bool flag without a value is true.
+	ArgumentTypeBool
+	// ArgumentTypeDuration accepts a Go duration such as `90s` or `1h30m`.
+	ArgumentTypeDuration
+	// ArgumentTypeUser accepts a @username, resolved to the corresponding *model.User.
+	ArgumentTypeUser
+	// ArgumentTypeChannel accepts a ~channel name of the current team, resolved to the
+	// corresponding *model.Channel.
+	ArgumentTypeChannel
+)
+
+// Values holds the parsed arguments of a command invocation, keyed by argument name.
+type Values struct {
+	// Fields are the raw words that follow the trigger of the executed command, with quotes
+	// removed.
+	Fields []string
+
+	values map[string]any
+}
+
+// Has returns true if the argument was given or has a default value.
+func (v *Values) Has(name string) bool {
+	_, ok := v.values[name]
+	return ok
+}
+
+// String returns the value of a string argument.
+func (v *Values) String(name string) string {
+	value, _ := v.values[name].(string)
+	return value
+}
+
+// Int returns the value of an integer argument.
+func (v *Values) Int(name string) int {
+	value, _ := v.values[name].(int)
+	return value
+}
+
+// Bool returns the value of a boolean argument.
+func (v *Values) Bool(name string) bool {
+	value, _ := v.values[name].(bool)
+	return value
+}
+
+// Duration returns the value of a duration argument.
+func (v *Values) Duration(name string) time.Duration {
+	value, _ := v.values[name].(time.Duration)
+	return value
+}
+
+// Strings returns the values of a variadic string argument.
+func (v *Values) Strings(name string) []string {
+	return list[string](v, name)
+}
+
+// Users returns the users referenced by a variadic user argument.
+func (v *Values) Users(name string) []*model.User {
+	return list[*model.User](v, name)
+}
+
+// Channels returns the channels referenced by a variadic channel argument.
+func (v *Values) Channels(name string) []*model.Channel {
+	return list[*model.Channel](v, name)
+}
+
+// list returns the values of a variadic argument having the type T.
+func list[T any](v *Values, name string) []T {
+	values, _ := v.values[name].([]any)
+
+	result := make([]T, 0, len(values))
+	for _, value := range values {
+		if t, ok := value.(T); ok {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// User returns the user referenced by a user argument.
+func (v *Values) User(name string) *model.User {
+	value, _ := v.values[name].(*model.User)
+	return value
+}
+
+// Channel returns the channel referenced by a channel argument.
+func (v *Values) Channel(name string) *model.Channel {
+	value, _ := v.values[name].(*model.Channel)
+	return value
+}
+
+// tokenize splits the command into words, honoring single and double quotes. Within double
+// quotes, a backslash escapes the next character. Single quotes only quote at the start of a
+// word, so that apostrophes, e.g. in `don't`, are kept as typed.
+func tokenize(command string) ([]string, error) {
+	tokens, _, err := splitCommand(command)
+	return tokens, err
+}
+
+// splitCommand splits the command into words like tokenize, also returning the byte offsets at
+// which they start in the command.
+func splitCommand(command string) ([]string, []int, error) {
+	var (
+		tokens  []string
+		offsets []int
+		current strings.Builder
+		inToken bool
+		quote   rune
+		escaped bool
+	)
+
+	for i, r := range command {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case quote != 0:
+			switch {
+			case r == '\\' && quote == '"':
+				escaped = true
+			case r == quote || (quote == '“' && r == '”'):
+				quote = 0
+			default:
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '“' || (r == '\'' && !inToken):
+			if !inToken {
+				offsets = append(offsets, i)
+			}
+			quote = r
+			inToken = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+		default:
+			if !inToken {
+				offsets = append(offsets, i)
+			}
+			current.WriteRune(r)
+			inToken = true
+		}
+	}
+
+	if quote != 0 || escaped {
+		return nil, nil, errors.New("unterminated quoted string")
+	}
+	if inToken {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens, offsets, nil
+}
+
+// rawRest returns the command as typed from the field at index i of fields on, given the fields
+// following the trigger of the command. It joins the fields with spaces if they do not end the
+// command, e.g. when the fields come from elsewhere.
+func rawRest(command string, fields []string, i int) string {
+	tokens, offsets, err := splitCommand(command)
+	if err != nil || len(tokens) < len(fields) || !slices.Equal(tokens[len(tokens)-len(fields):], fields) {
+		return strings.Join(fields[i:], " ")
+	}
+	return command[offsets[len(tokens)-len(fields)+i]:]
+}
+
+// quoteFields joins fields into a command line that tokenize splits back into the same fields,
+// quoting those that are empty or contain spaces, quotes or backslashes.
+func quoteFields(fields []string) string {
+	quoted := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if field != "" && !strings.ContainsAny(field, " \t\n\\\"'“") {
+			quoted = append(quoted, field)
+			continue
+		}
+		escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(field)
+		quoted = append(quoted, `"`+escaped+`"`)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// parse assigns the fields to the arguments and flags of the definition and converts each value
+// to its declared type. All validation errors are returned, one per line.
+func (c *Handler) parse(d *Definition, args *model.CommandArgs, fields []string) (*Values, error) {
+	values := &Values{
+		Fields: fields,
+		values: map[string]any{},
+	}
+
+	T := translate(args)
+	var problems []string
+	raw := map[*Argument][]string{}
+	var positional []string
+
+	var rest *Argument
+	if len(d.Arguments) > 0 && d.Arguments[len(d.Arguments)-1].Rest {
+		rest = d.Arguments[len(d.Arguments)-1]
+	}
+
+	flags := true
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		if flags && field == "--" {
+			flags = false
+			continue
+		}
+		if !flags || !isFlag(field) {
+			if rest != nil && len(positional) == len(d.Arguments)-1 {
+				raw[rest] = []string{rawRest(args.Command, fields, i)}
+				break
+			}
+			positional = append(positional, field)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(field, "-"), "=")
+		flag := d.flag(name, !strings.HasPrefix(field, "--"))
+		if flag == nil {
+			problems = append(problems, T("command.parse.unknown_flag", map[string]any{"Flag": field}))
+			continue
+		}
+
+		if !hasValue {
+			if flag.Type == ArgumentTypeBool {
+				value = "true"
+			} else if i+1 < len(fields) {
+				i++
+				value = fields[i]
+			} else {
+				problems = append(problems, T("command.parse.missing_value", map[string]any{"Flag": "--" + flag.Name}))
+				continue
+			}
+		}
+		raw[flag] = []string{value}
+	}
+
+	for i, value := range positional {
+		switch {
+		case i < len(d.Arguments):
+			raw[d.Arguments[i]] = append(raw[d.Arguments[i]], value)
+		case len(d.Arguments) > 0 && d.Arguments[len(d.Arguments)-1].Variadic:
+			last := d.Arguments[len(d.Arguments)-1]
+			raw[last] = append(raw[last], value)
+		default:
+			problems = append(problems, T("command.parse.unexpected_argument", map[string]any{"Argument": value}))
+		}
+	}
+
+	for _, argument := range slices.Concat(d.Arguments, d.Flags) {
+		rawValues, ok := raw[argument]
+		if !ok {
+			if argument.Required {
+				problems = append(problems, T("command.parse.missing_argument", map[string]any{"Argument": argument.display(d)}))
+				continue
+			}
+			if argument.Default == "" {
+				continue
+			}
+			rawValues = []string{argument.Default}
+		}
+
+		parsedValues := make([]any, 0, len(rawValues))
+		for _, value := range rawValues {
+			parsed, err := c.convert(argument, args, value)
+			if err != nil {
+				problems = append(problems, T("command.parse.invalid_value", map[string]any{"Argument": argument.display(d), "Error": err.Error()}))
+				continue
+			}
+			parsedValues = append(parsedValues, parsed)
+		}
+
+		if argument.Variadic {
+			values.values[argument.Name] = parsedValues
+		} else if len(parsedValues) == 1 {
+			values.values[argument.Name] = parsedValues[0]
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, errors.New(strings.Join(problems, "\n"))
+	}
+
+	return values, nil
+}
+
+// convert validates the raw value of the argument and converts it to the argument's type. The
+// errors are translated into the locale of the invoking user.
+func (c *Handler) convert(argument *Argument, args *model.CommandArgs, value string) (any, error) {
+	T := translate(args)
+	if len(argument.Options) > 0 && !slices.ContainsFunc(argument.Options, func(item model.AutocompleteListItem) bool {
+		return item.Item == value
+	}) {
+		return nil, errors.New(T("command.parse.not_allowed", map[string]any{"Value": value}))
+	}
+
+	switch argument.Type {
+	case ArgumentTypeInt:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, errors.New(T("command.parse.not_integer", map[string]any{"Value": value}))
+		}
+		return i, nil
+	case ArgumentTypeBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.New(T("command.parse.not_bool", map[string]any{"Value": value}))
+		}
+		return b, nil
+	case ArgumentTypeDuration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, errors.New(T("command.parse.not_duration", map[string]any{"Value": value}))
+		}
+		return d, nil
+	case ArgumentTypeUser:
+		username := strings.TrimPrefix(value, "@")
+		user, err := c.client.User.GetByUsername(username)
+		if err != nil {
+			return nil, errors.New(T("command.parse.user_not_found", map[string]any{"Username": username}))
+		}
+		return user, nil
+	case ArgumentTypeChannel:
+		name := strings.TrimPrefix(value, "~")
+		channel, err := c.client.Channel.GetByName(args.TeamId, name, false)
+		if err != nil {
+			return nil, errors.New(T("command.parse.channel_not_found", map[string]any{"Name": name}))
+		}
+		return channel, nil
+	default:
+		if argument.Pattern != "" {
+			matched, err := regexp.MatchString(argument.Pattern, value)
+			if err != nil || !matched {
+				return nil, errors.New(T("command.parse.no_match", map[string]any{"Value": value}))
+			}
+		}
+		return value, nil
+	}
+}
+
+// isFlag returns true if the field looks like a long or short flag rather than a value such as a
+// negative number.
+func isFlag(field string) bool {
+	if len(field) < 2 || field[0] != '-' {
+		return false
+	}
+	return field[1] < '0' || field[1] > '9'
+}
+
+// flag returns the flag with the given long or short name, or nil if there is none.
+func (d *Definition) flag(name string, short bool) *Argument {
+	for _, flag := range d.Flags {
+		if (!short && flag.Name == name) || (short && flag.Short != "" && flag.Short == name) {
+			return flag
+		}
+	}
+	return nil
+}
+
+// display returns how the argument is referred to in messages: `--name` for flags and `<name>`
+// for positional arguments.
+func (a *Argument) display(d *Definition) string {
+	if slices.Contains(d.Flags, a) {
+		return "`--" + a.Name + "`"
+	}
+	return "`<" + a.Name + ">`"
+}
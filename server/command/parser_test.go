@@ -0,0 +1,168 @@
+package command
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenize(t *testing.T) {
+	for name, tc := range map[string]struct {
+		command  string
+		expected []string
+	}{
+		"plain words":         {command: "/hello  world\tagain", expected: []string{"/hello", "world", "again"}},
+		"double quotes":       {command: `/hello "John Doe" x`, expected: []string{"/hello", "John Doe", "x"}},
+		"single quotes":       {command: `/hello 'it "is"'`, expected: []string{"/hello", `it "is"`}},
+		"escaped quote":       {command: `/hello "say \"hi\""`, expected: []string{"/hello", `say "hi"`}},
+		"quoted flag value":   {command: `/hello --message="good day"`, expected: []string{"/hello", "--message=good day"}},
+		"empty quoted string": {command: `/hello ""`, expected: []string{"/hello", ""}},
+		"smart quotes":        {command: "/hello “John Doe”", expected: []string{"/hello", "John Doe"}},
+		"apostrophes":         {command: `/hello don't, it's {{.Sender}}'s`, expected: []string{"/hello", "don't,", "it's", "{{.Sender}}'s"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tokens, err := tokenize(tc.command)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, tokens)
+		})
+	}
+
+	_, err := tokenize(`/hello "John`)
+	assert.Error(t, err)
+}
+
+func TestQuoteFields(t *testing.T) {
+	fields := []string{"/hello", "John Doe", "", `say "hi"`, `back\slash`, "it's", "--dm"}
+	command := quoteFields(fields)
+	assert.Equal(t, `/hello "John Doe" "" "say \"hi\"" "back\\slash" "it's" --dm`, command)
+
+	tokens, err := tokenize(command)
+	require.NoError(t, err)
+	assert.Equal(t, fields, tokens)
+}
+
+func TestParse(t *testing.T) {
+	env := setupTest()
+	handler := &Handler{client: env.client}
+
+	user := &model.User{Id: "user-id", Username: "alice"}
+	channel := &model.Channel{Id: "channel-id", Name: "town-square"}
+	env.api.On("GetUserByUsername", "alice").Return(user, nil)
+	env.api.On("GetUserByUsername", "nobody").Return(nil, model.NewAppError("GetUserByUsername", "app.user.missing", nil, "", http.StatusNotFound))
+	env.api.On("GetChannelByName", "team-id", "town-square", false).Return(channel, nil)
+
+	definition := &Definition{
+		Trigger: "remind",
+		Arguments: []*Argument{
+			{Name: "who", Type: ArgumentTypeUser, Required: true},
+			{Name: "message"},
+		},
+		Flags: []*Argument{
+			{Name: "in", Short: "i", Type: ArgumentTypeDuration, Default: "1h"},
+			{Name: "times", Short: "n", Type: ArgumentTypeInt, Default: "1"},
+			{Name: "urgent", Short: "u", Type: ArgumentTypeBool},
+			{Name: "channel", Type: ArgumentTypeChannel},
+			{Name: "level", Options: []model.AutocompleteListItem{{Item: "low"}, {Item: "high"}}},
+		},
+	}
+	args := &model.CommandArgs{TeamId: "team-id", T: testTranslations("en")}
+
+	t.Run("typed values", func(t *testing.T) {
+		values, err := handler.parse(definition, args, []string{"@alice", "--in=30m", "-n", "3", "-u", "--channel", "~town-square", "stand up"})
+		require.NoError(t, err)
+		assert.Equal(t, user, values.User("who"))
+		assert.Equal(t, "stand up", values.String("message"))
+		assert.Equal(t, 30*time.Minute, values.Duration("in"))
+		assert.Equal(t, 3, values.Int("times"))
+		assert.True(t, values.Bool("urgent"))
+		assert.Equal(t, channel, values.Channel("channel"))
+		assert.False(t, values.Has("level"))
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		values, err := handler.parse(definition, args, []string{"alice"})
+		require.NoError(t, err)
+		assert.Equal(t, time.Hour, values.Duration("in"))
+		assert.Equal(t, 1, values.Int("times"))
+		assert.False(t, values.Has("urgent"))
+	})
+
+	t.Run("arguments after double dash are positional", func(t *testing.T) {
+		values, err := handler.parse(definition, args, []string{"alice", "--", "--urgent"})
+		require.NoError(t, err)
+		assert.Equal(t, "--urgent", values.String("message"))
+		assert.False(t, values.Bool("urgent"))
+	})
+
+	t.Run("validation errors", func(t *testing.T) {
+		_, err := handler.parse(definition, args, []string{"@nobody", "a", "b", "--times=many", "--in", "soon", "--level", "medium", "--loud", "--channel"})
+		require.Error(t, err)
+		assert.Equal(t, "Unknown flag `--loud`.\n"+
+			"Missing value for `--channel`.\n"+
+			"Unexpected argument `b`.\n"+
+			"Invalid value for `<who>`: user @nobody not found.\n"+
+			"Invalid value for `--in`: `soon` is not a duration such as 30m or 1h30m.\n"+
+			"Invalid value for `--times`: `many` is not an integer.\n"+
+			"Invalid value for `--level`: `medium` is not one of the allowed values.", err.Error())
+	})
+
+	t.Run("variadic argument", func(t *testing.T) {
+		variadic := &Definition{
+			Trigger:   "greet",
+			Arguments: []*Argument{{Name: "greeting"}, {Name: "who", Type: ArgumentTypeUser, Variadic: true}},
+		}
+		env.api.On("GetUserByUsername", "bob").Return(&model.User{Id: "bob-id", Username: "bob"}, nil)
+
+		values, err := handler.parse(variadic, args, []string{"hi", "@alice", "@bob"})
+		require.NoError(t, err)
+		assert.Equal(t, "hi", values.String("greeting"))
+		users := values.Users("who")
+		require.Len(t, users, 2)
+		assert.Equal(t, "alice", users[0].Username)
+		assert.Equal(t, "bob", users[1].Username)
+	})
+
+	t.Run("rest argument", func(t *testing.T) {
+		rest := &Definition{
+			Trigger:   "note",
+			Arguments: []*Argument{{Name: "who", Type: ArgumentTypeUser}, {Name: "text", Rest: true}},
+			Flags:     []*Argument{{Name: "urgent", Type: ArgumentTypeBool}},
+		}
+		for name, tc := range map[string]struct {
+			command  string
+			expected string
+			urgent   bool
+		}{
+			"as typed":              {command: "/note @alice  Hi \"there\",\n-everyone -- it's --urgent ", expected: "Hi \"there\",\n-everyone -- it's --urgent "},
+			"after flags":           {command: "/note --urgent @alice Hi", expected: "Hi", urgent: true},
+			"after double dash":     {command: "/note @alice -- --urgent matters", expected: "--urgent matters"},
+			"fields from elsewhere": {command: "", expected: "Hi there"},
+		} {
+			t.Run(name, func(t *testing.T) {
+				fields := []string{"@alice", "Hi", "there"}
+				if tc.command != "" {
+					tokens, err := tokenize(tc.command)
+					require.NoError(t, err)
+					fields = tokens[1:]
+				}
+				restArgs := *args
+				restArgs.Command = tc.command
+				values, err := handler.parse(rest, &restArgs, fields)
+				require.NoError(t, err)
+				assert.Equal(t, "alice", values.User("who").Username)
+				assert.Equal(t, tc.expected, values.String("text"))
+				assert.Equal(t, tc.urgent, values.Bool("urgent"))
+			})
+		}
+	})
+
+	t.Run("missing required argument", func(t *testing.T) {
+		_, err := handler.parse(definition, args, nil)
+		require.Error(t, err)
+		assert.Equal(t, "Missing required argument `<who>`.", err.Error())
+	})
+}
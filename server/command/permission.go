@@ -0,0 +1,48 @@
+package command
+
+import (
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// hasPermission checks the permission required by the definition, if any, in the scope the
+// permission applies to: the system, the team or the channel the command was run in.
+func (c *Handler) hasPermission(d *Definition, args *model.CommandArgs) bool {
+	if d.Permission == nil {
+		return true
+	}
+
+	switch d.Permission.Scope {
+	case model.PermissionScopeTeam:
+		return c.client.User.HasPermissionToTeam(args.UserId, args.TeamId, d.Permission)
+	case model.PermissionScopeChannel:
+		return c.client.User.HasPermissionToChannel(args.UserId, args.ChannelId, d.Permission)
+	default:
+		return c.client.User.HasPermissionTo(args.UserId, d.Permission)
+	}
+}
+
+// authorize checks the permission required by the definition and records an audit log entry
+// for the attempt. The path holds the triggers leading to and including the definition.
+func (c *Handler) authorize(d *Definition, args *model.CommandArgs, path []string) bool {
+	if d.Permission == nil {
+		return true
+	}
+
+	permitted := c.hasPermission(d, args)
+
+	keyValuePairs := []any{
+		"audit", true,
+		"command", "/" + joinPath(path),
+		"permission", d.Permission.Id,
+		"user_id", args.UserId,
+		"team_id", args.TeamId,
+		"channel_id", args.ChannelId,
+	}
+	if permitted {
+		c.client.Log.Info("Slash command permitted", keyValuePairs...)
+	} else {
+		c.client.Log.Warn("Slash command not permitted", keyValuePairs...)
+	}
+
+	return permitted
+}
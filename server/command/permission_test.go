@@ -0,0 +1,56 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermissions(t *testing.T) {
+	env := setupTest()
+	handler := &Handler{client: env.client}
+
+	ok := func(args *model.CommandArgs, values *Values) (*model.CommandResponse, error) {
+		return &model.CommandResponse{Text: "ok"}, nil
+	}
+	root := &Definition{
+		Trigger: "hello",
+		Subcommands: []*Definition{
+			{
+				Trigger:     "admin",
+				Permission:  model.PermissionManageSystem,
+				Subcommands: []*Definition{{Trigger: "stats", Handler: ok}},
+			},
+			{Trigger: "rename", Permission: model.PermissionManagePublicChannelProperties, Handler: ok},
+		},
+	}
+
+	args := &model.CommandArgs{UserId: "user-id", TeamId: "team-id", ChannelId: "channel-id", T: testTranslations("en")}
+	env.api.On("HasPermissionTo", "user-id", model.PermissionManageSystem).Return(false)
+	env.api.On("HasPermissionToChannel", "user-id", "channel-id", model.PermissionManagePublicChannelProperties).Return(true)
+	env.api.On("LogWarn", "Slash command not permitted",
+		"audit", true, "command", "/hello admin", "permission", model.PermissionManageSystem.Id,
+		"user_id", "user-id", "team_id", "team-id", "channel_id", "channel-id").Return().Once()
+	env.api.On("LogInfo", "Slash command permitted",
+		"audit", true, "command", "/hello rename", "permission", model.PermissionManagePublicChannelProperties.Id,
+		"user_id", "user-id", "team_id", "team-id", "channel_id", "channel-id").Return().Once()
+
+	response, err := root.execute(handler, args, nil, []string{"admin", "stats"})
+	require.NoError(t, err)
+	assert.Equal(t, model.CommandResponseTypeEphemeral, response.ResponseType)
+	assert.Equal(t, "You do not have permission to run this command.", response.Text)
+
+	response, err = root.execute(handler, args, nil, []string{"rename"})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", response.Text)
+
+	env.api.AssertExpectations(t)
+
+	t.Run("system permissions hide autocomplete from non-admins", func(t *testing.T) {
+		data := root.autocompleteData(nil)
+		assert.Equal(t, model.SystemAdminRoleId, data.SubCommands[0].RoleID)
+		assert.Equal(t, model.SystemUserRoleId, data.SubCommands[1].RoleID)
+	})
+}
@@ -0,0 +1,366 @@
+package command
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-starter-template/server/events"
+	"github.com/mattermost/mattermost-plugin-starter-template/server/store/kvstore"
+)
+
+const (
+	// maxScheduledCommands is the maximum number of pending scheduled commands per user.
+	maxScheduledCommands = 25
+
+	// maxScheduleDelay is how far ahead commands can be scheduled.
+	maxScheduleDelay = 90 * 24 * time.Hour
+
+	// defaultScheduleHour is the time of day used when only a day is given, e.g. `tomorrow`.
+	defaultScheduleHour = 9
+
+	// scheduleTimeLayout formats when scheduled commands run, in the user's timezone.
+	scheduleTimeLayout = "Mon, Jan 2 at 15:04 MST"
+)
+
+// daysPattern matches delays in days, which time.ParseDuration does not support.
+var daysPattern = regexp.MustCompile(`^(\d+)d$`)
+
+// clockLayouts are the accepted formats of a time of day.
+var clockLayouts = []string{"15:04", "3:04pm", "3pm"}
+
+// parseSchedule parses the time at the start of the fields, relative to now and in its location.
+// It returns when to run and the remaining fields, or false if the fields do not start with a
+// time.
+func parseSchedule(fields []string, now time.Time) (time.Time, []string, bool) {
+	if len(fields) == 0 {
+		return time.Time{}, nil, false
+	}
+
+	word := strings.ToLower(fields[0])
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch word {
+	case "in":
+		if len(fields) < 2 {
+			return time.Time{}, nil, false
+		}
+		delay, ok := parseDelay(fields[1])
+		if !ok {
+			return time.Time{}, nil, false
+		}
+		return now.Add(delay), fields[2:], true
+	case "today", "tomorrow":
+		day := today
+		if word == "tomorrow" {
+			day = today.AddDate(0, 0, 1)
+		}
+		if len(fields) > 1 {
+			if hour, minute, ok := parseClock(fields[1]); ok {
+				return atClock(day, hour, minute), fields[2:], true
+			}
+		}
+		if word == "today" {
+			return time.Time{}, nil, false
+		}
+		return atClock(day, defaultScheduleHour, 0), fields[1:], true
+	}
+
+	if day, err := time.ParseInLocation("2006-01-02", word, now.Location()); err == nil {
+		if len(fields) > 1 {
+			if hour, minute, ok := parseClock(fields[1]); ok {
+				return atClock(day, hour, minute), fields[2:], true
+			}
+		}
+		return atClock(day, defaultScheduleHour, 0), fields[1:], true
+	}
+
+	if hour, minute, ok := parseClock(word); ok {
+		runAt := atClock(today, hour, minute)
+		if !runAt.After(now) {
+			runAt = atClock(today.AddDate(0, 0, 1), hour, minute)
+		}
+		return runAt, fields[1:], true
+	}
+
+	return time.Time{}, nil, false
+}
+
+// parseDelay parses a positive delay such as `30m`, `1h30m` or `3d`.
+func parseDelay(value string) (time.Duration, bool) {
+	if match := daysPattern.FindStringSubmatch(value); match != nil {
+		days, err := strconv.Atoi(match[1])
+		if err != nil || days <= 0 {
+			return 0, false
+		}
+		return time.Duration(days) * 24 * time.Hour, true
+	}
+
+	delay, err := time.ParseDuration(value)
+	if err != nil || delay <= 0 {
+		return 0, false
+	}
+	return delay, true
+}
+
+// parseClock parses a time of day such as `17:30`, `5:30pm` or `5pm`.
+func parseClock(value string) (hour, minute int, ok bool) {
+	for _, layout := range clockLayouts {
+		if t, err := time.Parse(layout, strings.ToLower(value)); err == nil {
+			return t.Hour(), t.Minute(), true
+		}
+	}
+	return 0, 0, false
+}
+
+// atClock returns the given time of day on the day, in its location.
+func atClock(day time.Time, hour, minute int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, day.Location())
+}
+
+func (c *Handler) executeScheduleCommand(args *model.CommandArgs, values *Values) (*model.CommandResponse, error) {
+	T := translate(args)
+	user, err := c.client.User.Get(args.UserId)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get user")
+	}
+	now := time.Now().In(user.GetTimezoneLocation())
+
+	runAt, fields, ok := parseSchedule(values.Fields, now)
+	if !ok || len(fields) == 0 {
+		return &model.CommandResponse{
+			ResponseType: model.CommandResponseTypeEphemeral,
+			Text:         T("command.schedule.usage"),
+		}, nil
+	}
+	if !runAt.After(now) || runAt.Sub(now) > maxScheduleDelay {
+		return &model.CommandResponse{
+			ResponseType: model.CommandResponseTypeEphemeral,
+			Text:         T("command.schedule.out_of_range", map[string]any{"Days": int(maxScheduleDelay.Hours() / 24)}),
+		}, nil
+	}
+
+	// Commands are relative to the trigger the schedule command was run with, unless they
+	// start with a trigger of their own.
+	if !strings.HasPrefix(fields[0], "/") {
+		fields = append([]string{"/" + trigger(args)}, fields...)
+	}
+	path, fields := declaredPath(c.activeDefinitions(), fields)
+	if path == nil {
+		return &model.CommandResponse{
+			ResponseType: model.CommandResponseTypeEphemeral,
+			Text:         T("command.schedule.foreign_command", map[string]any{"Command": fields[0]}),
+		}, nil
+	}
+
+	scheduled, err := c.kvstore.ListScheduledCommands(args.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if len(scheduled) >= maxScheduledCommands {
+		return &model.CommandResponse{
+			ResponseType: model.CommandResponseTypeEphemeral,
+			Text:         T("command.schedule.too_many", map[string]any{"Count": len(scheduled), "Trigger": trigger(args)}),
+		}, nil
+	}
+
+	command := &kvstore.ScheduledCommand{
+		ID:        model.NewId(),
+		UserID:    args.UserId,
+		TeamID:    args.TeamId,
+		ChannelID: args.ChannelId,
+		RootID:    args.RootId,
+		Path:      path,
+		Arguments: quoteFields(fields),
+		RunAt:     runAt.UnixMilli(),
+	}
+	if err := c.kvstore.SaveScheduledCommand(command); err != nil {
+		return nil, err
+	}
+
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         T("command.schedule.scheduled", map[string]any{"Command": c.commandLine(command), "Time": runAt.Format(scheduleTimeLayout)}),
+	}, nil
+}
+
+// commandLine returns the command line of a scheduled command, with the triggers currently
+// configured for its path. Triggers no longer in use are kept as declared.
+func (c *Handler) commandLine(command *kvstore.ScheduledCommand) string {
+	path := slices.Clone(command.Path)
+	definitions := c.activeDefinitions()
+	for i, declared := range command.Path {
+		index := slices.IndexFunc(definitions, func(d *Definition) bool { return strings.EqualFold(d.declaredTrigger(), declared) })
+		if index < 0 {
+			break
+		}
+		path[i] = definitions[index].Trigger
+		definitions = definitions[index].Subcommands
+	}
+
+	line := "/" + strings.Join(path, " ")
+	if command.Arguments != "" {
+		line += " " + command.Arguments
+	}
+	return line
+}
+
+func (c *Handler) executeScheduleListCommand(args *model.CommandArgs, values *Values) (*model.CommandResponse, error) {
+	T := translate(args)
+	user, err := c.client.User.Get(args.UserId)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get user")
+	}
+
+	scheduled, err := c.kvstore.ListScheduledCommands(args.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if len(scheduled) == 0 {
+		return &model.CommandResponse{
+			ResponseType: model.CommandResponseTypeEphemeral,
+			Text:         T("command.schedule.list.empty"),
+		}, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(T("command.schedule.list.header"))
+	for _, command := range scheduled {
+		runAt := time.UnixMilli(command.RunAt).In(user.GetTimezoneLocation())
+		sb.WriteString("\n- " + T("command.schedule.list.item", map[string]any{"Command": c.commandLine(command), "Time": runAt.Format(scheduleTimeLayout), "ID": command.ID}))
+	}
+
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         sb.String(),
+	}, nil
+}
+
+func (c *Handler) executeScheduleCancelCommand(args *model.CommandArgs, values *Values) (*model.CommandResponse, error) {
+	T := translate(args)
+	id := values.String("id")
+
+	scheduled, err := c.kvstore.ListScheduledCommands(args.UserId)
+	if err != nil {
+		return nil, err
+	}
+	for _, command := range scheduled {
+		if command.ID != id {
+			continue
+		}
+
+		deleted, err := c.kvstore.DeleteScheduledCommand(command)
+		if err != nil {
+			return nil, err
+		}
+		if !deleted {
+			break
+		}
+		return &model.CommandResponse{
+			ResponseType: model.CommandResponseTypeEphemeral,
+			Text:         T("command.schedule.cancelled", map[string]any{"Command": c.commandLine(command)}),
+		}, nil
+	}
+
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         T("command.schedule.not_found", map[string]any{"ID": id}),
+	}, nil
+}
+
+// suggestScheduledCommands suggests the IDs of the scheduled commands of the user.
+func (c *Handler) suggestScheduledCommands(args *model.CommandArgs, userInput string) ([]model.AutocompleteListItem, error) {
+	scheduled, err := c.kvstore.ListScheduledCommands(args.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]model.AutocompleteListItem, 0, len(scheduled))
+	for _, command := range scheduled {
+		items = append(items, model.AutocompleteListItem{
+			Item:     command.ID,
+			HelpText: c.commandLine(command),
+		})
+	}
+	return items, nil
+}
+
+// RunScheduledCommands runs the scheduled commands that are due on behalf of the users who
+// scheduled them, delivering their responses like those of background commands. Each command is
+// deleted before it runs, so that it runs at most once even if cluster nodes race to run it.
+func (c *Handler) RunScheduledCommands(now time.Time) error {
+	scheduled, err := c.kvstore.ListDueScheduledCommands(now)
+	if err != nil {
+		return err
+	}
+
+	for _, command := range scheduled {
+		claimed, err := c.kvstore.DeleteScheduledCommand(command)
+		if err != nil {
+			c.client.Log.Warn("Failed to claim scheduled command", "id", command.ID, "user_id", command.UserID, "error", err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		c.runScheduledCommand(command)
+	}
+
+	return nil
+}
+
+// runScheduledCommand runs a scheduled command as the user who scheduled it, provided they are
+// still active and may still post in the channel. Otherwise the command is dropped, and the user
+// told so with a direct message.
+func (c *Handler) runScheduledCommand(command *kvstore.ScheduledCommand) {
+	user, err := c.client.User.Get(command.UserID)
+	if err != nil {
+		c.client.Log.Info("Skipped scheduled command of an unavailable user", "id", command.ID, "user_id", command.UserID)
+		return
+	}
+
+	// The command runs with the triggers configured by then.
+	line := c.commandLine(command)
+	args := &model.CommandArgs{
+		UserId:    command.UserID,
+		TeamId:    command.TeamID,
+		ChannelId: command.ChannelID,
+		RootId:    command.RootID,
+		Command:   line,
+		T:         c.translationsFor(user),
+	}
+
+	if user.DeleteAt != 0 || !c.client.User.HasPermissionToChannel(command.UserID, command.ChannelID, model.PermissionCreatePost) {
+		c.client.Log.Info("Dropped scheduled command of a user who can no longer post in the channel", "id", command.ID, "user_id", command.UserID, "channel_id", command.ChannelID)
+		post := &model.Post{Message: args.T("command.schedule.dropped", map[string]any{"Command": line})}
+		if err := c.client.Post.DM(c.botUserID, command.UserID, post); err != nil {
+			c.client.Log.Warn("Failed to notify the user of a dropped scheduled command", "id", command.ID, "user_id", command.UserID, "error", err)
+		}
+		return
+	}
+
+	// Users were rate limited when scheduling the command, not when it runs.
+	response, err := c.handle(args, false)
+	if err != nil {
+		c.client.Log.Warn("Scheduled slash command failed", "id", command.ID, "user_id", command.UserID, "error", err)
+		response = &model.CommandResponse{
+			ResponseType: model.CommandResponseTypeEphemeral,
+			Text:         args.T("command.schedule.failed", map[string]any{"Command": line, "Error": err.Error()}),
+		}
+	}
+
+	c.deliver(args, response)
+
+	events.ScheduledCommandRan.Publish(&c.client.Frontend, events.ScheduledCommandRanPayload{
+		ID:        command.ID,
+		Command:   line,
+		ChannelID: command.ChannelID,
+		Failed:    err != nil,
+	}, events.ToUser(command.UserID))
+}
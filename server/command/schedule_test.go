@@ -0,0 +1,234 @@
+package command
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-starter-template/server/store/kvstore"
+)
+
+func TestParseSchedule(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("timezone data is not available")
+	}
+	now := time.Date(2024, time.March, 14, 16, 20, 0, 0, location)
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, time.March, day, hour, minute, 0, 0, location)
+	}
+
+	for name, tc := range map[string]struct {
+		fields   []string
+		expected time.Time
+		rest     []string
+	}{
+		"delay":               {fields: []string{"in", "2h", "@alice"}, expected: now.Add(2 * time.Hour), rest: []string{"@alice"}},
+		"delay in days":       {fields: []string{"in", "3d", "@alice"}, expected: now.Add(72 * time.Hour), rest: []string{"@alice"}},
+		"tomorrow":            {fields: []string{"tomorrow", "@alice"}, expected: at(15, 9, 0), rest: []string{"@alice"}},
+		"tomorrow at":         {fields: []string{"Tomorrow", "9:30", "@alice"}, expected: at(15, 9, 30), rest: []string{"@alice"}},
+		"today at":            {fields: []string{"today", "5pm", "@alice"}, expected: at(14, 17, 0), rest: []string{"@alice"}},
+		"later today":         {fields: []string{"17:45", "@alice"}, expected: at(14, 17, 45), rest: []string{"@alice"}},
+		"time already passed": {fields: []string{"8:15am", "@alice"}, expected: at(15, 8, 15), rest: []string{"@alice"}},
+		"date":                {fields: []string{"2024-03-20", "18:00", "@alice"}, expected: at(20, 18, 0), rest: []string{"@alice"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			runAt, rest, ok := parseSchedule(tc.fields, now)
+			assert.True(t, ok)
+			assert.True(t, tc.expected.Equal(runAt), "expected %s, got %s", tc.expected, runAt)
+			assert.Equal(t, tc.rest, rest)
+		})
+	}
+
+	for _, fields := range [][]string{nil, {"in"}, {"in", "soon"}, {"in", "-1h"}, {"today", "@alice"}, {"@alice"}} {
+		_, _, ok := parseSchedule(fields, now)
+		assert.False(t, ok, "%v", fields)
+	}
+}
+
+// mockKVStore backs the KV store of the API with a map, honoring atomic updates. KVList is not
+// mocked, so that the tests fail if the scheduled commands are found by paging through the keys.
+func mockKVStore(api *plugintest.API) map[string][]byte {
+	stored := map[string][]byte{}
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte { return stored[key] }, nil)
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(func(key string, value []byte, options model.PluginKVSetOptions) bool {
+		if options.Atomic && !bytes.Equal(stored[key], options.OldValue) {
+			return false
+		}
+		if value == nil {
+			delete(stored, key)
+		} else {
+			stored[key] = value
+		}
+		return true
+	}, nil)
+	return stored
+}
+
+func TestRunScheduledCommands(t *testing.T) {
+	env := setupTest()
+	stored := mockKVStore(env.api)
+	store := kvstore.NewKVStore(env.client)
+	handler := &Handler{client: env.client, kvstore: store, botUserID: "bot-id", translations: testTranslations}
+	handler.definitions = []*Definition{{
+		Trigger:   "hello",
+		Arguments: []*Argument{{Name: "targets", Variadic: true}},
+		Handler: func(args *model.CommandArgs, values *Values) (*model.CommandResponse, error) {
+			return &model.CommandResponse{
+				ResponseType: model.CommandResponseTypeInChannel,
+				Text:         "Hello, " + joinNames(args.T, values.Strings("targets")) + " from " + args.UserId,
+			}, nil
+		},
+	}}
+	handler.LimitRate(rateLimiterFunc(func(userID string, names ...string) (bool, time.Duration) {
+		t.Error("the runs of scheduled commands are not rate limited")
+		return false, time.Minute
+	}))
+
+	due := &kvstore.ScheduledCommand{ID: "due", UserID: "user-id", ChannelID: "channel-id", Path: []string{"hello"}, Arguments: "@bob", RunAt: 9000}
+	taken := &kvstore.ScheduledCommand{ID: "taken", UserID: "user-id", ChannelID: "channel-id", Path: []string{"hello"}, Arguments: "@carol", RunAt: 9500}
+	later := &kvstore.ScheduledCommand{ID: "later", UserID: "user-id", ChannelID: "channel-id", Path: []string{"hello"}, Arguments: "@dave", RunAt: 20000}
+	for _, command := range []*kvstore.ScheduledCommand{due, taken, later} {
+		require.NoError(t, store.SaveScheduledCommand(command))
+	}
+	// Another server ran the command, leaving its entries in the indexes.
+	delete(stored, "schedule-user-id-taken")
+
+	env.api.On("GetUser", "user-id").Return(&model.User{Id: "user-id", Username: "alice"}, nil)
+	env.api.On("HasPermissionToChannel", "user-id", "channel-id", model.PermissionCreatePost).Return(true)
+	env.api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.UserId == "bot-id" && post.ChannelId == "channel-id" && post.Message == "Hello, @bob from user-id"
+	})).Return(&model.Post{}, nil).Once()
+	env.api.On("PublishWebSocketEvent", "scheduled_command_ran", map[string]any{"id": "due", "command": "/hello @bob", "channel_id": "channel-id", "failed": false}, &model.WebsocketBroadcast{UserId: "user-id"}).Return().Once()
+
+	now := time.UnixMilli(10_000)
+	assert.NoError(t, handler.RunScheduledCommands(now))
+	env.api.AssertExpectations(t)
+	env.api.AssertNumberOfCalls(t, "CreatePost", 1)
+
+	remaining, err := store.ListScheduledCommands("user-id")
+	require.NoError(t, err)
+	assert.Equal(t, []*kvstore.ScheduledCommand{later}, remaining)
+
+	remaining, err = store.ListDueScheduledCommands(now)
+	require.NoError(t, err)
+	assert.Empty(t, remaining)
+}
+
+func TestRunScheduledCommandsOfUsersWhoCannotPost(t *testing.T) {
+	for name, tc := range map[string]struct {
+		user       *model.User
+		permission bool
+	}{
+		"removed from the channel": {user: &model.User{Id: "user-id", Username: "alice"}},
+		"deactivated":              {user: &model.User{Id: "user-id", Username: "alice", DeleteAt: 1}, permission: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			env := setupTest()
+			mockKVStore(env.api)
+			store := kvstore.NewKVStore(env.client)
+			handler := &Handler{client: env.client, kvstore: store, botUserID: "bot-id", translations: testTranslations}
+			handler.definitions = []*Definition{{
+				Trigger: "hello",
+				Handler: func(args *model.CommandArgs, values *Values) (*model.CommandResponse, error) {
+					t.Fatal("the command ran")
+					return nil, nil
+				},
+			}}
+
+			require.NoError(t, store.SaveScheduledCommand(&kvstore.ScheduledCommand{ID: "due", UserID: "user-id", ChannelID: "channel-id", Path: []string{"hello"}, RunAt: 9000}))
+			env.api.On("GetUser", "user-id").Return(tc.user, nil)
+			env.api.On("HasPermissionToChannel", "user-id", "channel-id", model.PermissionCreatePost).Return(tc.permission).Maybe()
+			env.api.On("LogInfo", "Dropped scheduled command of a user who can no longer post in the channel", "id", "due", "user_id", "user-id", "channel_id", "channel-id").Return().Once()
+			env.api.On("GetDirectChannel", "bot-id", "user-id").Return(&model.Channel{Id: "dm-id"}, nil)
+			env.api.On("CreatePost", &model.Post{
+				UserId:    "bot-id",
+				ChannelId: "dm-id",
+				Message:   "Scheduled command `/hello` did not run, as you can no longer post in the channel it was scheduled in.",
+			}).Return(&model.Post{}, nil).Once()
+
+			assert.NoError(t, handler.RunScheduledCommands(time.UnixMilli(10_000)))
+			env.api.AssertExpectations(t)
+
+			remaining, err := store.ListScheduledCommands("user-id")
+			require.NoError(t, err)
+			assert.Empty(t, remaining)
+		})
+	}
+}
+
+func TestScheduleCommand(t *testing.T) {
+	env := setupTest()
+	mockKVStore(env.api)
+	store := kvstore.NewKVStore(env.client)
+	handler := &Handler{client: env.client, kvstore: store}
+	handler.definitions = []*Definition{{
+		Trigger:     "hello",
+		Subcommands: []*Definition{{Trigger: "schedule", Raw: true, Handler: handler.executeScheduleCommand}},
+	}}
+
+	env.api.On("GetUser", "user-id").Return(&model.User{Id: "user-id", Timezone: model.StringMap{"manualTimezone": "UTC"}}, nil)
+
+	args := &model.CommandArgs{UserId: "user-id", ChannelId: "channel-id", Command: `/hello schedule in 2h --dm @alice "good morning"`, T: testTranslations("en")}
+	response, err := handler.dispatch(args)
+	assert.NoError(t, err)
+	assert.Contains(t, response.Text, "`/hello --dm @alice \"good morning\"` will run on ")
+
+	scheduled, err := store.ListScheduledCommands("user-id")
+	require.NoError(t, err)
+	require.Len(t, scheduled, 1)
+	assert.Equal(t, []string{"hello"}, scheduled[0].Path)
+	assert.Equal(t, `--dm @alice "good morning"`, scheduled[0].Arguments)
+
+	args.Command = "/hello schedule whenever @alice"
+	response, err = handler.dispatch(args)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(response.Text, "Tell me when to run the command"))
+}
+
+func TestRunScheduledCommandsWithAnotherTrigger(t *testing.T) {
+	env := setupTest()
+	mockKVStore(env.api)
+	store := kvstore.NewKVStore(env.client)
+	handler := &Handler{client: env.client, kvstore: store, botUserID: "bot-id", translations: testTranslations}
+	hello := &Definition{
+		Trigger: "hello",
+		Subcommands: []*Definition{
+			{Trigger: "schedule", Raw: true, Handler: handler.executeScheduleCommand},
+			{Trigger: "wave", Raw: true, Handler: func(args *model.CommandArgs, values *Values) (*model.CommandResponse, error) {
+				return &model.CommandResponse{ResponseType: model.CommandResponseTypeInChannel, Text: "Ran " + args.Command}, nil
+			}},
+		},
+	}
+	handler.definitions = []*Definition{hello}
+
+	env.api.On("GetUser", "user-id").Return(&model.User{Id: "user-id", Username: "alice", Timezone: model.StringMap{"manualTimezone": "UTC"}}, nil)
+	args := &model.CommandArgs{UserId: "user-id", ChannelId: "channel-id", Command: "/hello schedule in 2h Wave @bob", T: testTranslations("en")}
+	response, err := handler.dispatch(args)
+	require.NoError(t, err)
+	assert.Contains(t, response.Text, "`/hello wave @bob` will run on ")
+
+	// The administrator changed the trigger before the command ran.
+	handler.active = []*Definition{configureDefinition(hello, Config{}, "hi", nil, nil)}
+
+	scheduled, err := store.ListScheduledCommands("user-id")
+	require.NoError(t, err)
+	require.Len(t, scheduled, 1)
+	assert.Equal(t, []string{"hello", "wave"}, scheduled[0].Path)
+
+	env.api.On("HasPermissionToChannel", "user-id", "channel-id", model.PermissionCreatePost).Return(true)
+	env.api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.Message == "Ran /hi wave @bob"
+	})).Return(&model.Post{}, nil).Once()
+	env.api.On("PublishWebSocketEvent", "scheduled_command_ran", map[string]any{"id": scheduled[0].ID, "command": "/hi wave @bob", "channel_id": "channel-id", "failed": false}, &model.WebsocketBroadcast{UserId: "user-id"}).Return().Once()
+
+	assert.NoError(t, handler.RunScheduledCommands(time.Now().Add(3*time.Hour)))
+	env.api.AssertExpectations(t)
+}
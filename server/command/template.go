@@ -0,0 +1,262 @@
+package command
+
+import (
+	"io"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"unicode/utf8"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/pkg/errors"
+)
+
+// maxGreetingTemplateLength is the maximum length of a greeting template, in characters.
+const maxGreetingTemplateLength = 1000
+
+// templateDialogID identifies the dialog editing the greeting template.
+const templateDialogID = "greeting_template"
+
+// greetingData holds the fields available to greeting templates.
+type greetingData struct {
+	// Recipient is who is greeted, e.g. `@alice and ~town-square`.
+	Recipient string
+
+	// Sender is the @username of the user saying hello.
+	Sender string
+
+	// Channel is the ~name of the channel the command was run in.
+	Channel string
+}
+
+// greetingFields are the fields of greetingData templates may reference.
+var greetingFields = map[string]bool{"Recipient": true, "Sender": true, "Channel": true}
+
+// sampleGreetingData is used to check templates when they are saved.
+var sampleGreetingData = greetingData{
+	Recipient: "@alice",
+	Sender:    "@bob",
+	Channel:   "~town-square",
+}
+
+// parseGreetingTemplate parses a greeting template, and executes it against sample data so that
+// references to unknown fields are reported when the template is saved rather than used.
+func parseGreetingTemplate(text string) (*template.Template, error) {
+	if utf8.RuneCountInString(text) > maxGreetingTemplateLength {
+		return nil, errors.Errorf("the template must be at most %d characters", maxGreetingTemplateLength)
+	}
+
+	tmpl, err := template.New("greeting").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGreetingTemplate(tmpl); err != nil {
+		return nil, err
+	}
+	if err := tmpl.Execute(io.Discard, sampleGreetingData); err != nil {
+		return nil, err
+	}
+
+	return tmpl, nil
+}
+
+// checkGreetingTemplate only allows text, comments and references to the greeting fields, e.g.
+// `{{.Sender}}`, so that rendering a template takes time proportional to its length. Actions
+// such as range, if or template, and pipelines and function calls are rejected.
+func checkGreetingTemplate(tmpl *template.Template) error {
+	invalid := errors.New("templates may only contain text and the {{.Recipient}}, {{.Sender}} and {{.Channel}} fields")
+	if len(tmpl.Templates()) > 1 {
+		return invalid
+	}
+	if tmpl.Tree == nil {
+		return nil
+	}
+
+	for _, node := range tmpl.Tree.Root.Nodes {
+		switch node := node.(type) {
+		case *parse.TextNode, *parse.CommentNode:
+		case *parse.ActionNode:
+			if len(node.Pipe.Decl) > 0 || len(node.Pipe.Cmds) != 1 || len(node.Pipe.Cmds[0].Args) != 1 {
+				return invalid
+			}
+			field, ok := node.Pipe.Cmds[0].Args[0].(*parse.FieldNode)
+			if !ok || len(field.Ident) != 1 || !greetingFields[field.Ident[0]] {
+				return invalid
+			}
+		default:
+			return invalid
+		}
+	}
+	return nil
+}
+
+// greeter renders greetings with the template of the user saying hello.
+type greeter struct {
+	log pluginapi.LogService
+
+	// tmpl is the user's template, or nil to use the default greetings.
+	tmpl *template.Template
+	data greetingData
+}
+
+// newGreeter loads the greeting template of the invoking user. Failures are logged, and the
+// default greetings used instead.
+func (c *Handler) newGreeter(args *model.CommandArgs) *greeter {
+	g := &greeter{log: c.client.Log}
+
+	text, err := c.kvstore.GetTemplateData(args.UserId)
+	if err != nil {
+		c.client.Log.Warn("Failed to get greeting template", "user_id", args.UserId, "error", err)
+		return g
+	}
+	if text == "" {
+		return g
+	}
+
+	tmpl, err := parseGreetingTemplate(text)
+	if err != nil {
+		c.client.Log.Warn("Failed to parse greeting template", "user_id", args.UserId, "error", err)
+		return g
+	}
+
+	if sender, err := c.client.User.Get(args.UserId); err == nil {
+		g.data.Sender = "@" + sender.Username
+	}
+	if channel, err := c.client.Channel.Get(args.ChannelId); err == nil {
+		g.data.Channel = "~" + channel.Name
+	}
+	g.tmpl = tmpl
+
+	return g
+}
+
+// greet renders the greeting for the recipient, or returns the fallback if the user has no
+// template or it fails to render.
+func (g *greeter) greet(recipient, fallback string) string {
+	if g.tmpl == nil {
+		return fallback
+	}
+
+	data := g.data
+	data.Recipient = recipient
+
+	var sb strings.Builder
+	if err := g.tmpl.Execute(&sb, data); err != nil {
+		g.log.Warn("Failed to render greeting template", "error", err)
+		return fallback
+	}
+	if strings.TrimSpace(sb.String()) == "" {
+		return fallback
+	}
+
+	return sb.String()
+}
+
+func (c *Handler) executeTemplateSetCommand(args *model.CommandArgs, values *Values) (*model.CommandResponse, error) {
+	T := translate(args)
+	text := values.String("template")
+
+	tmpl, err := parseGreetingTemplate(text)
+	if err != nil {
+		return &model.CommandResponse{
+			ResponseType: model.CommandResponseTypeEphemeral,
+			Text:         T("command.template.invalid", map[string]any{"Error": err.Error()}),
+		}, nil
+	}
+
+	if err := c.kvstore.SetTemplateData(args.UserId, text); err != nil {
+		return nil, err
+	}
+
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         T("command.template.saved", map[string]any{"Preview": previewGreeting(tmpl)}),
+	}, nil
+}
+
+// previewGreeting renders the greeting template with the sample data.
+func previewGreeting(tmpl *template.Template) string {
+	var preview strings.Builder
+	_ = tmpl.Execute(&preview, sampleGreetingData)
+	return preview.String()
+}
+
+// executeTemplateEditCommand opens the dialog editing the greeting template, filled with the
+// current template.
+func (c *Handler) executeTemplateEditCommand(args *model.CommandArgs, values *Values) (*model.CommandResponse, error) {
+	text, err := c.kvstore.GetTemplateData(args.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.openDialog(args, templateDialogID, "", map[string]string{"template": text}); err != nil {
+		return nil, err
+	}
+
+	return &model.CommandResponse{}, nil
+}
+
+// validateTemplateDialog checks the greeting template submitted with the dialog.
+func (c *Handler) validateTemplateDialog(submission *DialogSubmission) map[string]string {
+	text, _ := submission.Request.Submission["template"].(string)
+	if _, err := parseGreetingTemplate(text); err != nil {
+		T := c.userTranslations(submission.Request.UserId)
+		return map[string]string{"template": T("command.template.invalid", map[string]any{"Error": err.Error()})}
+	}
+	return nil
+}
+
+// submitTemplateDialog stores the greeting template submitted with the dialog, once validated,
+// and shows the user a preview.
+func (c *Handler) submitTemplateDialog(submission *DialogSubmission) (*model.SubmitDialogResponse, error) {
+	userID := submission.Request.UserId
+	text, _ := submission.Request.Submission["template"].(string)
+	tmpl, err := parseGreetingTemplate(text)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.kvstore.SetTemplateData(userID, text); err != nil {
+		return nil, err
+	}
+
+	T := c.userTranslations(userID)
+	c.client.Post.SendEphemeralPost(userID, &model.Post{
+		ChannelId: submission.Request.ChannelId,
+		Message:   T("command.template.saved", map[string]any{"Preview": previewGreeting(tmpl)}),
+	})
+
+	return nil, nil
+}
+
+func (c *Handler) executeTemplateShowCommand(args *model.CommandArgs, values *Values) (*model.CommandResponse, error) {
+	T := translate(args)
+	text, err := c.kvstore.GetTemplateData(args.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if text == "" {
+		return &model.CommandResponse{
+			ResponseType: model.CommandResponseTypeEphemeral,
+			Text:         T("command.template.none"),
+		}, nil
+	}
+
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         T("command.template.show", map[string]any{"Template": text}),
+	}, nil
+}
+
+func (c *Handler) executeTemplateClearCommand(args *model.CommandArgs, values *Values) (*model.CommandResponse, error) {
+	if err := c.kvstore.DeleteTemplateData(args.UserId); err != nil {
+		return nil, err
+	}
+
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         translate(args)("command.template.cleared"),
+	}, nil
+}
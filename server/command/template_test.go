@@ -0,0 +1,173 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-starter-template/server/store/kvstore"
+)
+
+func TestParseGreetingTemplate(t *testing.T) {
+	for name, tc := range map[string]struct {
+		text  string
+		valid bool
+	}{
+		"all fields":    {text: "Hi {{.Recipient}}, {{.Sender}} says hello from {{.Channel}}!", valid: true},
+		"plain text":    {text: "Howdy", valid: true},
+		"unknown field": {text: "Hi {{.Nickname}}"},
+		"syntax error":  {text: "Hi {{.Recipient"},
+		"too long":      {text: strings.Repeat("a", maxGreetingTemplateLength+1)},
+		"comment":       {text: "Hi {{/* a comment */}}{{.Recipient}}", valid: true},
+		"range":         {text: "{{range 100000}}{{range 100000}}{{.}}{{end}}{{end}}"},
+		"if":            {text: "{{if .Sender}}Hi{{end}}"},
+		"with":          {text: "{{with .Sender}}{{.}}{{end}}"},
+		"define":        {text: `{{define "x"}}Hi{{end}}{{template "x"}}`},
+		"pipeline":      {text: "{{.Sender | printf \"%s%s\" .Recipient}}"},
+		"function":      {text: "{{print .Sender}}"},
+		"variable":      {text: "{{$x := .Sender}}{{$x}}"},
+		"nested field":  {text: "{{.Sender.Length}}"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseGreetingTemplate(tc.text)
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestGreetingTemplates(t *testing.T) {
+	setup := func(template string) (*env, *Handler, *model.CommandArgs) {
+		env := setupTest()
+		stored := []byte(nil)
+		if template != "" {
+			stored = []byte(`"` + template + `"`)
+		}
+		env.api.On("KVGet", "template_key-sender-id").Return(stored, nil)
+		env.api.On("GetUser", "sender-id").Return(&model.User{Id: "sender-id", Username: "sender"}, nil)
+		env.api.On("GetChannel", "channel-id").Return(&model.Channel{Id: "channel-id", Name: "off-topic"}, nil)
+		env.api.On("GetUserByUsername", "alice").Return(&model.User{Id: "alice-id", Username: "alice"}, nil)
+
+		handler := &Handler{client: env.client, kvstore: kvstore.NewKVStore(env.client), botUserID: "bot-id"}
+		args := &model.CommandArgs{UserId: "sender-id", TeamId: "team-id", ChannelId: "channel-id", T: testTranslations("en")}
+		return env, handler, args
+	}
+
+	hello := func(t *testing.T, handler *Handler, args *model.CommandArgs, targets ...any) *model.CommandResponse {
+		response, err := handler.executeHelloCommand(args, &Values{values: map[string]any{"targets": targets}})
+		require.NoError(t, err)
+		return response
+	}
+
+	t.Run("renders the sender's template", func(t *testing.T) {
+		_, handler, args := setup("Hi {{.Recipient}}, {{.Sender}} says hello from {{.Channel}}!")
+		response := hello(t, handler, args, "@alice")
+		assert.Equal(t, "Hi @alice, @sender says hello from ~off-topic!", response.Text)
+	})
+
+	t.Run("falls back to the default greeting", func(t *testing.T) {
+		env, handler, args := setup("Hi {{.Nickname}}")
+		env.api.On("LogWarn", "Failed to parse greeting template", "user_id", "sender-id", "error", mock.Anything).Return().Once()
+
+		response := hello(t, handler, args, "@alice")
+		assert.Equal(t, "Hello, @alice", response.Text)
+		env.api.AssertNumberOfCalls(t, "LogWarn", 1)
+	})
+
+	t.Run("set validates and stores the template", func(t *testing.T) {
+		env, handler, args := setup("")
+		env.api.On("KVSetWithOptions", "template_key-sender-id", []byte(`"Hi {{.Recipient}}"`), model.PluginKVSetOptions{}).Return(true, nil).Once()
+
+		response, err := handler.executeTemplateSetCommand(args, &Values{values: map[string]any{"template": "Hi {{.Recipient}}"}})
+		require.NoError(t, err)
+		assert.Equal(t, model.CommandResponseTypeEphemeral, response.ResponseType)
+		assert.Contains(t, response.Text, "> Hi @alice")
+
+		response, err = handler.executeTemplateSetCommand(args, &Values{values: map[string]any{"template": "Hi {{.Recipient"}})
+		require.NoError(t, err)
+		assert.Contains(t, response.Text, "Invalid greeting template:")
+		env.api.AssertNumberOfCalls(t, "KVSetWithOptions", 1)
+	})
+
+	t.Run("set stores the template as typed", func(t *testing.T) {
+		env, handler, args := setup("")
+		handler.definitions = []*Definition{{Trigger: "hello", Subcommands: []*Definition{{Trigger: "template", Subcommands: []*Definition{{
+			Trigger:   "set",
+			Arguments: []*Argument{{Name: "template", Required: true, Rest: true}},
+			Handler:   handler.executeTemplateSetCommand,
+		}}}}}}
+		text := "Hey  {{.Recipient}},\n-- it's {{.Sender}} -here"
+		env.api.On("KVSetWithOptions", "template_key-sender-id", []byte(`"Hey  {{.Recipient}},\n-- it's {{.Sender}} -here"`), model.PluginKVSetOptions{}).Return(true, nil).Once()
+
+		invocation := *args
+		invocation.Command = "/hello template set " + text
+		response, err := handler.Handle(&invocation)
+		require.NoError(t, err)
+		assert.Contains(t, response.Text, "> Hey  @alice,")
+		env.api.AssertNumberOfCalls(t, "KVSetWithOptions", 1)
+	})
+
+	t.Run("edit in a dialog", func(t *testing.T) {
+		env, handler, args := setup("Howdy")
+		handler.pluginID = "plugin-id"
+		handler.translations = testTranslations
+		handler.dialogs = map[string]*Dialog{}
+		handler.definitions = []*Definition{{Trigger: "hello", Subcommands: []*Definition{{Trigger: "template", Subcommands: []*Definition{{
+			Trigger: "edit",
+			Handler: handler.executeTemplateEditCommand,
+			Dialogs: []*Dialog{{
+				ID:       templateDialogID,
+				Dialog:   model.Dialog{Elements: []model.DialogElement{{Name: "template", Type: "textarea"}}},
+				Validate: handler.validateTemplateDialog,
+				Submit:   handler.submitTemplateDialog,
+			}},
+		}}}}}}
+		indexDialogs(handler.dialogs, handler.definitions)
+
+		env.api.On("OpenInteractiveDialog", mock.MatchedBy(func(request model.OpenDialogRequest) bool {
+			return request.Dialog.CallbackId == templateDialogID+":" && request.Dialog.Elements[0].Default == "Howdy"
+		})).Return(nil).Once()
+		args.Command = "/hello template edit"
+		response, err := handler.dispatch(args)
+		require.NoError(t, err)
+		assert.Empty(t, response.Text)
+
+		request := DialogSubmitRequest(templateDialogID, "", "sender-id", map[string]any{"template": "Hi {{.Nickname}}"})
+		request.ChannelId = "channel-id"
+		submitted, err := handler.SubmitDialog(request)
+		require.NoError(t, err)
+		assert.Contains(t, submitted.Errors["template"], "Invalid greeting template:")
+
+		env.api.On("KVSetWithOptions", "template_key-sender-id", []byte(`"Hi {{.Recipient}}"`), model.PluginKVSetOptions{}).Return(true, nil).Once()
+		env.api.On("SendEphemeralPost", "sender-id", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "channel-id" && strings.Contains(post.Message, "> Hi @alice")
+		})).Return(&model.Post{}).Once()
+		request.Submission["template"] = "Hi {{.Recipient}}"
+		submitted, err = handler.SubmitDialog(request)
+		require.NoError(t, err)
+		assert.Nil(t, submitted)
+		env.api.AssertNumberOfCalls(t, "OpenInteractiveDialog", 1)
+		env.api.AssertNumberOfCalls(t, "KVSetWithOptions", 1)
+		env.api.AssertNumberOfCalls(t, "SendEphemeralPost", 1)
+	})
+
+	t.Run("show and clear", func(t *testing.T) {
+		env, handler, args := setup("Howdy")
+		response, err := handler.executeTemplateShowCommand(args, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "Your greeting template is:\n```\nHowdy\n```", response.Text)
+
+		env.api.On("KVSetWithOptions", "template_key-sender-id", []byte(nil), model.PluginKVSetOptions{}).Return(true, nil).Once()
+		response, err = handler.executeTemplateClearCommand(args, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "Your greeting template is cleared, so the default greeting is used.", response.Text)
+		env.api.AssertNumberOfCalls(t, "KVSetWithOptions", 1)
+	})
+}
@@ -0,0 +1,83 @@
+package command
+
+import (
+	"slices"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+// waveBackActionID identifies the action of the buttons and menus answering a wave.
+const waveBackActionID = "wave_back"
+
+// senderIDContextKey holds the ID of the user waving in the context of the wave back action.
+const senderIDContextKey = "sender_id"
+
+// waveReplies are the emojis users may wave back with, the first one being the default.
+var waveReplies = []string{"wave", "raised_hands", "handshake"}
+
+// executeWaveCommand posts a wave from the bot, with a button and a menu letting the other
+// members of the channel wave back.
+func (c *Handler) executeWaveCommand(args *model.CommandArgs, values *Values) (*model.CommandResponse, error) {
+	T := translate(args)
+	sender, err := c.client.User.Get(args.UserId)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get sender")
+	}
+	recipient := values.User("user")
+
+	post := &model.Post{
+		UserId:    c.botUserID,
+		ChannelId: args.ChannelId,
+		RootId:    args.RootId,
+		Message:   T("command.wave.message", map[string]any{"Sender": sender.Username, "Recipient": recipient.Username}),
+	}
+	err = c.createPostWithActions(post, func(postID string) ([]*model.SlackAttachment, error) {
+		context := map[string]any{senderIDContextKey: sender.Id}
+		button, err := c.newButton(postID, waveBackActionID, T("command.wave.button"), "primary", context)
+		if err != nil {
+			return nil, err
+		}
+
+		options := []*model.PostActionOptions{
+			{Text: T("command.wave.reply.wave"), Value: "wave"},
+			{Text: T("command.wave.reply.raised_hands"), Value: "raised_hands"},
+			{Text: T("command.wave.reply.handshake"), Value: "handshake"},
+		}
+		menu, err := c.newSelect(postID, waveBackActionID, T("command.wave.menu"), options, context)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*model.SlackAttachment{{Actions: []*model.PostAction{button, menu}}}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.CommandResponse{}, nil
+}
+
+// handleWaveBack adds the reply of the user to the wave, with the emoji selected in the menu or
+// the default one for the button. The user who waved cannot wave back.
+func (c *Handler) handleWaveBack(request *ActionRequest) (*model.PostActionIntegrationResponse, error) {
+	user, err := c.client.User.Get(request.Request.UserId)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get user")
+	}
+	T := c.translationsFor(user)
+
+	if senderID, _ := request.Context[senderIDContextKey].(string); senderID == user.Id {
+		return &model.PostActionIntegrationResponse{EphemeralText: T("command.wave.self")}, nil
+	}
+
+	// The selected option is not signed, so it is checked against the options of the menu.
+	emoji := waveReplies[0]
+	if slices.Contains(waveReplies, request.SelectedOption) {
+		emoji = request.SelectedOption
+	}
+
+	return c.updatePost(request, func(post *model.Post) {
+		post.Message += "\n" + T("command.wave.waved_back", map[string]any{"User": user.Username, "Emoji": emoji})
+	})
+}
@@ -0,0 +1,85 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWave(t *testing.T) {
+	env := setupTest()
+	handler := &Handler{client: env.client, pluginID: "plugin-id", botUserID: "bot-id", translations: testTranslations, actions: map[string]*Action{}, signingKey: []byte("signing-key")}
+	handler.definitions = []*Definition{{
+		Trigger: "hello",
+		Subcommands: []*Definition{{
+			Trigger:   "wave",
+			Arguments: []*Argument{{Name: "user", Type: ArgumentTypeUser, Required: true}},
+			Handler:   handler.executeWaveCommand,
+			Actions:   []*Action{{ID: waveBackActionID, Handle: handler.handleWaveBack}},
+		}},
+	}}
+	indexActions(handler.actions, handler.definitions)
+
+	env.api.On("GetUser", "sender-id").Return(&model.User{Id: "sender-id", Username: "sender"}, nil)
+	env.api.On("GetUser", "user-id").Return(&model.User{Id: "user-id", Username: "alice"}, nil)
+	env.api.On("GetUserByUsername", "alice").Return(&model.User{Id: "user-id", Username: "alice"}, nil)
+	env.api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.UserId == "bot-id" && post.ChannelId == "channel-id" && post.Message == "@sender waves at @alice :wave:" && len(post.Attachments()) == 0
+	})).Return(func(post *model.Post) *model.Post {
+		created := post.Clone()
+		created.Id = "post-id"
+		return created
+	}, nil).Once()
+
+	var posted *model.Post
+	env.api.On("UpdatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+		posted = post.Clone()
+		return posted
+	}, nil).Once()
+
+	args := &model.CommandArgs{UserId: "sender-id", ChannelId: "channel-id", Command: "/hello wave @alice", T: testTranslations("en")}
+	response, err := handler.dispatch(args)
+	require.NoError(t, err)
+	assert.Empty(t, response.Text)
+
+	require.NotNil(t, posted)
+	assert.Equal(t, "post-id", posted.Id)
+	require.Len(t, posted.Attachments(), 1)
+	actions := posted.Attachments()[0].Actions
+	require.Len(t, actions, 2)
+	button, menu := actions[0], actions[1]
+	assert.Equal(t, "Wave back", button.Name)
+	assert.Equal(t, []*model.PostActionOptions{{Text: "A wave", Value: "wave"}, {Text: "Raised hands", Value: "raised_hands"}, {Text: "A handshake", Value: "handshake"}}, menu.Options)
+
+	env.api.On("GetPost", "post-id").Return(func(string) *model.Post {
+		return &model.Post{Id: "post-id", Message: "@sender waves at @alice :wave:"}
+	}, nil)
+
+	t.Run("button", func(t *testing.T) {
+		response, err := handler.HandleAction(actionRequest(t, button, ""))
+		require.NoError(t, err)
+		assert.Equal(t, "@sender waves at @alice :wave:\n@alice waved back :wave:", response.Update.Message)
+	})
+
+	t.Run("menu", func(t *testing.T) {
+		response, err := handler.HandleAction(actionRequest(t, menu, "handshake"))
+		require.NoError(t, err)
+		assert.Equal(t, "@sender waves at @alice :wave:\n@alice waved back :handshake:", response.Update.Message)
+
+		response, err = handler.HandleAction(actionRequest(t, menu, "**bold**"))
+		require.NoError(t, err)
+		assert.Equal(t, "@sender waves at @alice :wave:\n@alice waved back :wave:", response.Update.Message, "only the options of the menu are accepted")
+	})
+
+	t.Run("sender", func(t *testing.T) {
+		request := actionRequest(t, button, "")
+		request.UserId = "sender-id"
+		response, err := handler.HandleAction(request)
+		require.NoError(t, err)
+		assert.Nil(t, response.Update)
+		assert.Equal(t, "You cannot wave back at yourself.", response.EphemeralText)
+	})
+}
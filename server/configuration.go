@@ -2,8 +2,12 @@ package main
 
 import (
 	"reflect"
+	"strings"
 
 	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-starter-template/server/command"
+	"github.com/mattermost/mattermost-plugin-starter-template/server/ratelimit"
 )
 
 // configuration captures the plugin's external configuration as exposed in the Mattermost server
@@ -17,7 +21,23 @@ import (
 //
 // If you add non-reference types to your configuration struct, be sure to rewrite Clone as a deep
 // copy appropriate for your types.
-type configuration struct{}
+type configuration struct {
+	// HelloTrigger replaces the trigger of the hello command, e.g. to avoid a collision with
+	// another plugin.
+	HelloTrigger string
+
+	// DisabledCommands is a comma separated list of the commands to disable, e.g. `hello alias`.
+	DisabledCommands string
+
+	// AllowedPlugins is a comma separated list of the IDs of the plugins allowed to call the
+	// plugin API, e.g. `com.example.plugin`.
+	AllowedPlugins string
+
+	// RateLimits is a comma separated list of the rate limits of every user, for the `api` and
+	// `plugin` route groups or for commands by their default triggers, e.g. `api: 120/m,
+	// /hello schedule: 5/10m`.
+	RateLimits string
+}
 
 // Clone shallow copies the configuration. Your implementation may require a deep copy if
 // your configuration has reference types.
@@ -26,6 +46,40 @@ func (c *configuration) Clone() *configuration {
 	return &clone
 }
 
+// commandConfig returns the configuration of the slash commands.
+func (c *configuration) commandConfig() command.Config {
+	config := command.Config{
+		Triggers: map[string]string{
+			command.HelloCommandTrigger: c.HelloTrigger,
+		},
+	}
+	for _, disabled := range strings.Split(c.DisabledCommands, ",") {
+		if disabled = strings.TrimSpace(disabled); disabled != "" {
+			config.Disabled = append(config.Disabled, disabled)
+		}
+	}
+	return config
+}
+
+// rateLimits returns the rate limits, by route group or command.
+func (c *configuration) rateLimits() (map[string]ratelimit.Limit, error) {
+	limits, err := ratelimit.ParseLimits(c.RateLimits)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid rate limits")
+	}
+	return limits, nil
+}
+
+// isPluginAllowed returns true if the plugin with the given ID may call the plugin API.
+func (c *configuration) isPluginAllowed(pluginID string) bool {
+	for _, allowed := range strings.Split(c.AllowedPlugins, ",") {
+		if allowed = strings.TrimSpace(allowed); allowed != "" && allowed == pluginID {
+			return true
+		}
+	}
+	return false
+}
+
 // getConfiguration retrieves the active configuration under lock, making it safe to use
 // concurrently. The active configuration may change underneath the client of this method, but
 // the struct returned by this API call is considered immutable.
@@ -76,7 +130,20 @@ func (p *Plugin) OnConfigurationChange() error {
 		return errors.Wrap(err, "failed to load plugin configuration")
 	}
 
+	rateLimits, err := configuration.rateLimits()
+	if err != nil {
+		return err
+	}
+
 	p.setConfiguration(configuration)
 
+	// The commands are configured on activation, as this hook first runs before OnActivate.
+	if p.commandClient != nil {
+		p.commandClient.Configure(configuration.commandConfig())
+	}
+	if p.rateLimiter != nil {
+		p.rateLimiter.Configure(rateLimits)
+	}
+
 	return nil
 }
@@ -0,0 +1,49 @@
+package events
+
+// ScheduledCommandRanPayload is the payload of ScheduledCommandRan.
+type ScheduledCommandRanPayload struct {
+	// ID is the ID of the scheduled command.
+	ID string `json:"id"`
+
+	// Command is the slash command that ran.
+	Command string `json:"command"`
+
+	// ChannelID is the channel the command ran in.
+	ChannelID string `json:"channel_id"`
+
+	// Failed is whether the command failed.
+	Failed bool `json:"failed"`
+}
+
+// ScheduledCommandRan is sent to a user when one of their scheduled commands ran.
+var ScheduledCommandRan = define[ScheduledCommandRanPayload]("scheduled_command_ran")
+
+// TeamAliasesChangedPayload is the payload of TeamAliasesChanged.
+type TeamAliasesChangedPayload struct {
+	// TeamID is the team whose aliases changed.
+	TeamID string `json:"team_id"`
+
+	// Name is the name of the alias that was added, replaced or removed.
+	Name string `json:"name"`
+
+	// Removed is whether the alias was removed.
+	Removed bool `json:"removed"`
+}
+
+// TeamAliasesChanged is sent to the members of a team when its command aliases changed.
+var TeamAliasesChanged = define[TeamAliasesChangedPayload]("team_aliases_changed")
+
+// WebhookPostedPayload is the payload of WebhookPosted.
+type WebhookPostedPayload struct {
+	// WebhookID is the ID of the incoming webhook.
+	WebhookID string `json:"webhook_id"`
+
+	// ChannelID is the channel the webhook posted to.
+	ChannelID string `json:"channel_id"`
+
+	// PostID is the ID of the post.
+	PostID string `json:"post_id"`
+}
+
+// WebhookPosted is sent to the members of a channel when an incoming webhook posted to it.
+var WebhookPosted = define[WebhookPostedPayload]("webhook_posted")
@@ -0,0 +1,150 @@
+// Package events defines the WebSocket events the plugin publishes to the webapp.
+//
+// Every event has a name and a payload struct, defined once here:
+//
+//	events.TeamAliasesChanged.Publish(&client.Frontend, events.TeamAliasesChangedPayload{...}, events.ToTeam(teamID))
+//
+// The TypeScript types of the payloads are generated from these definitions by `make apply`, into
+// webapp/src/websocket_events.ts, so that the webapp registers its handlers type-safely.
+package events
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+// Publisher publishes WebSocket events, such as plugin.API or pluginapi.FrontendService.
+type Publisher interface {
+	PublishWebSocketEvent(event string, payload map[string]any, broadcast *model.WebsocketBroadcast)
+}
+
+// Definition describes an event, independently of the type of its payload.
+type Definition interface {
+	// Name is the name of the event. The webapp receives it prefixed with
+	// `custom_<pluginid>_`.
+	Name() string
+
+	// PayloadType is the struct type of the payload.
+	PayloadType() reflect.Type
+}
+
+// Event is an event with a payload of type P.
+type Event[P any] struct {
+	name string
+}
+
+// namePattern matches the valid event names.
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// definitions are all defined events, in the order they were defined.
+var definitions []Definition
+
+// define defines an event with a payload of type P. It panics if the name is invalid or taken, or
+// if the payload is not a struct of primitive fields, which are all the server can deliver.
+func define[P any](name string) *Event[P] {
+	if !namePattern.MatchString(name) {
+		panic(fmt.Sprintf("invalid event name %q", name))
+	}
+	for _, definition := range definitions {
+		if definition.Name() == name {
+			panic(fmt.Sprintf("event %q is defined twice", name))
+		}
+	}
+	if err := checkPayloadType(reflect.TypeFor[P]()); err != nil {
+		panic(fmt.Sprintf("invalid payload of event %q: %s", name, err))
+	}
+
+	event := &Event[P]{name: name}
+	definitions = append(definitions, event)
+	return event
+}
+
+// All returns all defined events, in the order they were defined.
+func All() []Definition {
+	return append([]Definition(nil), definitions...)
+}
+
+func (e *Event[P]) Name() string {
+	return e.name
+}
+
+func (e *Event[P]) PayloadType() reflect.Type {
+	return reflect.TypeFor[P]()
+}
+
+// Publish publishes the event with the payload to the recipients of the broadcast.
+func (e *Event[P]) Publish(publisher Publisher, payload P, broadcast *model.WebsocketBroadcast) {
+	publisher.PublishWebSocketEvent(e.name, payloadMap(payload), broadcast)
+}
+
+// ToUser targets the WebSocket connections of a user.
+func ToUser(userID string) *model.WebsocketBroadcast {
+	return &model.WebsocketBroadcast{UserId: userID}
+}
+
+// ToChannel targets the WebSocket connections of the members of a channel.
+func ToChannel(channelID string) *model.WebsocketBroadcast {
+	return &model.WebsocketBroadcast{ChannelId: channelID}
+}
+
+// ToTeam targets the WebSocket connections of the members of a team.
+func ToTeam(teamID string) *model.WebsocketBroadcast {
+	return &model.WebsocketBroadcast{TeamId: teamID}
+}
+
+// payloadField is a field of a payload struct.
+type payloadField struct {
+	index int
+	name  string
+}
+
+// payloadFields returns the exported fields of a payload struct, named by their JSON tags.
+func payloadFields(t reflect.Type) []payloadField {
+	var fields []payloadField
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, payloadField{index: i, name: name})
+	}
+	return fields
+}
+
+// checkPayloadType checks that the payload is a struct of primitive fields.
+func checkPayloadType(t reflect.Type) error {
+	if t.Kind() != reflect.Struct {
+		return errors.Errorf("%s is not a struct", t)
+	}
+	for _, field := range payloadFields(t) {
+		if _, ok := primitives[t.Field(field.index).Type.Kind()]; !ok {
+			return errors.Errorf("field %s is a %s, not a primitive type", t.Field(field.index).Name, t.Field(field.index).Type)
+		}
+	}
+	return nil
+}
+
+// payloadMap converts the payload to the map the server publishes. Fields of named types are
+// converted to their primitive types, which the server can encode.
+func payloadMap(payload any) map[string]any {
+	value := reflect.ValueOf(payload)
+	fields := payloadFields(value.Type())
+	data := make(map[string]any, len(fields))
+	for _, field := range fields {
+		fieldValue := value.Field(field.index)
+		data[field.name] = fieldValue.Convert(primitives[fieldValue.Kind()].goType).Interface()
+	}
+	return data
+}
@@ -0,0 +1,86 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+)
+
+type testState string
+
+type testPayload struct {
+	Name    string    `json:"name"`
+	State   testState `json:"state,omitempty"`
+	Count   int       `json:"count"`
+	Ignored string    `json:"-"`
+	Default bool
+	private string
+}
+
+func TestPublish(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("PublishWebSocketEvent", "team_aliases_changed", map[string]any{"team_id": "team-id", "name": "standup", "removed": true}, &model.WebsocketBroadcast{TeamId: "team-id"}).Return().Once()
+
+	TeamAliasesChanged.Publish(api, TeamAliasesChangedPayload{TeamID: "team-id", Name: "standup", Removed: true}, ToTeam("team-id"))
+	api.AssertExpectations(t)
+}
+
+func TestPayloadMap(t *testing.T) {
+	payload := testPayload{Name: "name", State: "done", Count: 2, Ignored: "ignored", Default: true, private: "private"}
+	data := payloadMap(payload)
+	assert.Equal(t, map[string]any{"name": "name", "state": "done", "count": 2, "Default": true}, data)
+	assert.IsType(t, "", data["state"], "named types are published as primitives")
+}
+
+func TestDefine(t *testing.T) {
+	assert.PanicsWithValue(t, `invalid event name "Invalid-Name"`, func() { define[testPayload]("Invalid-Name") })
+	assert.PanicsWithValue(t, `event "webhook_posted" is defined twice`, func() { define[testPayload]("webhook_posted") })
+	assert.Panics(t, func() { define[string]("string_payload") })
+	assert.Panics(t, func() { define[struct{ IDs []string }]("slice_payload") })
+
+	names := make([]string, 0, len(All()))
+	for _, definition := range All() {
+		names = append(names, definition.Name())
+	}
+	assert.Equal(t, []string{"scheduled_command_ran", "team_aliases_changed", "webhook_posted"}, names)
+}
+
+func TestBroadcasts(t *testing.T) {
+	assert.Equal(t, &model.WebsocketBroadcast{UserId: "user-id"}, ToUser("user-id"))
+	assert.Equal(t, &model.WebsocketBroadcast{ChannelId: "channel-id"}, ToChannel("channel-id"))
+	assert.Equal(t, &model.WebsocketBroadcast{TeamId: "team-id"}, ToTeam("team-id"))
+}
+
+func TestTypeScript(t *testing.T) {
+	assert.Equal(t, `// This file is automatically generated. Do not modify it manually.
+
+export type ScheduledCommandRanPayload = {
+    id: string;
+    command: string;
+    channel_id: string;
+    failed: boolean;
+};
+
+export type TeamAliasesChangedPayload = {
+    team_id: string;
+    name: string;
+    removed: boolean;
+};
+
+export type WebhookPostedPayload = {
+    webhook_id: string;
+    channel_id: string;
+    post_id: string;
+};
+
+export type WebSocketEvents = {
+    scheduled_command_ran: ScheduledCommandRanPayload;
+    team_aliases_changed: TeamAliasesChangedPayload;
+    webhook_posted: WebhookPostedPayload;
+};
+
+export type WebSocketEventName = keyof WebSocketEvents;
+`, TypeScript())
+}
@@ -0,0 +1,66 @@
+package events
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// primitive is a type of payload field.
+type primitive struct {
+	// goType is the predeclared Go type published for fields of this kind.
+	goType reflect.Type
+
+	// typeScript is the TypeScript type of the fields.
+	typeScript string
+}
+
+// primitives are the supported types of payload fields, by kind.
+var primitives = map[reflect.Kind]primitive{
+	reflect.String:  {reflect.TypeFor[string](), "string"},
+	reflect.Bool:    {reflect.TypeFor[bool](), "boolean"},
+	reflect.Int:     {reflect.TypeFor[int](), "number"},
+	reflect.Int8:    {reflect.TypeFor[int8](), "number"},
+	reflect.Int16:   {reflect.TypeFor[int16](), "number"},
+	reflect.Int32:   {reflect.TypeFor[int32](), "number"},
+	reflect.Int64:   {reflect.TypeFor[int64](), "number"},
+	reflect.Uint:    {reflect.TypeFor[uint](), "number"},
+	reflect.Uint8:   {reflect.TypeFor[uint8](), "number"},
+	reflect.Uint16:  {reflect.TypeFor[uint16](), "number"},
+	reflect.Uint32:  {reflect.TypeFor[uint32](), "number"},
+	reflect.Uint64:  {reflect.TypeFor[uint64](), "number"},
+	reflect.Float32: {reflect.TypeFor[float32](), "number"},
+	reflect.Float64: {reflect.TypeFor[float64](), "number"},
+}
+
+const typeScriptHeader = `// This file is automatically generated. Do not modify it manually.
+
+`
+
+// TypeScript returns the TypeScript module declaring the payload types of the events:
+//
+//   - a type per payload, named after its Go type,
+//   - WebSocketEvents, mapping the event names to their payload types, and
+//   - WebSocketEventName, the union of the event names.
+func TypeScript() string {
+	var b strings.Builder
+	b.WriteString(typeScriptHeader)
+
+	for _, definition := range definitions {
+		t := definition.PayloadType()
+		fmt.Fprintf(&b, "export type %s = {\n", t.Name())
+		for _, field := range payloadFields(t) {
+			fmt.Fprintf(&b, "    %s: %s;\n", field.name, primitives[t.Field(field.index).Type.Kind()].typeScript)
+		}
+		b.WriteString("};\n\n")
+	}
+
+	b.WriteString("export type WebSocketEvents = {\n")
+	for _, definition := range definitions {
+		fmt.Fprintf(&b, "    %s: %s;\n", definition.Name(), definition.PayloadType().Name())
+	}
+	b.WriteString("};\n\n")
+	b.WriteString("export type WebSocketEventName = keyof WebSocketEvents;\n")
+
+	return b.String()
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+// maxRequestBodySize bounds the size of the JSON bodies decoded for typed handlers.
+const maxRequestBodySize = 1 << 20
+
+// Request is a request to a typed API handler.
+type Request struct {
+	*http.Request
+
+	// UserID is the ID of the user making the request, as authenticated by the server.
+	UserID string
+
+	// PluginID is the ID of the plugin making the request, for the requests of other plugins.
+	PluginID string
+
+	// Logger logs with the ID of the request.
+	Logger *Logger
+}
+
+// Var returns the route variable with the given name, e.g. `team_id` for `/teams/{team_id}`.
+func (r *Request) Var(name string) string {
+	return mux.Vars(r.Request)[name]
+}
+
+// HandlerFunc handles an API request whose JSON body is decoded into In, responding with Out
+// encoded as JSON, or with 204 No Content if Out is Empty. Errors are sent as a *model.AppError
+// with its status code: return one to choose the status code and message, while other errors
+// are logged and reported as internal errors.
+type HandlerFunc[In, Out any] func(r *Request, in *In) (Out, error)
+
+// Empty is the request or response type of handlers without a body.
+type Empty struct{}
+
+// validator is implemented by request types validating their fields once decoded.
+type validator interface {
+	IsValid() *model.AppError
+}
+
+// handleJSON registers a typed handler for the method and path on the router, documented with
+// the request and response types of the handler.
+func handleJSON[In, Out any](p *Plugin, router *mux.Router, method, path string, doc RouteDoc, handler HandlerFunc[In, Out]) *mux.Route {
+	route := router.Handle(path, jsonHandler(p, handler)).Methods(method)
+	p.document(route, doc, bodyType(*new(In)), bodyType(*new(Out)))
+	return route
+}
+
+// jsonHandler adapts a typed handler to an http.Handler, decoding and validating the request
+// and encoding the response or error.
+func jsonHandler[In, Out any](p *Plugin, handler HandlerFunc[In, Out]) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := new(In)
+		if appErr := decodeRequest(w, r, in); appErr != nil {
+			p.writeError(w, r, appErr)
+			return
+		}
+
+		out, err := handler(&Request{
+			Request:  r,
+			UserID:   r.Header.Get("Mattermost-User-ID"),
+			PluginID: r.Header.Get("Mattermost-Plugin-ID"),
+			Logger:   p.requestLogger(r),
+		}, in)
+		if err != nil {
+			p.writeError(w, r, err)
+			return
+		}
+		if _, ok := any(out).(Empty); ok {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		p.writeJSON(w, http.StatusOK, out)
+	})
+}
+
+// decodeRequest decodes the JSON body of the request into in, unless it is Empty, and validates
+// it.
+func decodeRequest(w http.ResponseWriter, r *http.Request, in any) *model.AppError {
+	if _, ok := in.(*Empty); ok {
+		return nil
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	if err := decoder.Decode(in); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			return model.NewAppError("decodeRequest", "api.error.body_too_large", map[string]any{"Max": maxBytesErr.Limit}, "", http.StatusRequestEntityTooLarge)
+		case errors.Is(err, io.EOF):
+			return model.NewAppError("decodeRequest", "api.error.missing_body", nil, "", http.StatusBadRequest)
+		default:
+			return model.NewAppError("decodeRequest", "api.error.invalid_body", nil, err.Error(), http.StatusBadRequest)
+		}
+	}
+
+	if v, ok := in.(validator); ok {
+		if appErr := v.IsValid(); appErr != nil {
+			if appErr.StatusCode == 0 {
+				appErr.StatusCode = http.StatusBadRequest
+			}
+			return appErr
+		}
+	}
+
+	return nil
+}
+
+// writeError responds with the error as a *model.AppError translated into the locale of the
+// user, with the ID of the request. Errors other than *model.AppError are logged and reported as internal errors, without
+// disclosing their details.
+func (p *Plugin) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var appErr *model.AppError
+	if !errors.As(err, &appErr) {
+		p.requestLogger(r).Error("Failed to handle API request", "method", r.Method, "path", r.URL.Path, "error", err)
+		appErr = model.NewAppError("writeError", "api.error.internal", nil, "", http.StatusInternalServerError)
+	}
+	if appErr.StatusCode == 0 {
+		appErr.StatusCode = http.StatusInternalServerError
+	}
+	appErr.RequestId = requestID(r)
+
+	appErr.Translate(p.requestTranslations(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(appErr.StatusCode)
+	if _, err := io.WriteString(w, appErr.ToJSON()); err != nil {
+		p.requestLogger(r).Error("Failed to write response", "error", err)
+	}
+}
+
+// writeJSON responds with the value encoded as JSON.
+func (p *Plugin) writeJSON(w http.ResponseWriter, statusCode int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		p.API.LogError("Failed to write response", "error", err)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/mattermost/mattermost/server/public/shared/i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type greetRequest struct {
+	Name string `json:"name"`
+}
+
+func (r *greetRequest) IsValid() *model.AppError {
+	if r.Name == "" {
+		return model.NewAppError("IsValid", "api.error.invalid_body", nil, "name is required", 0)
+	}
+	return nil
+}
+
+type greetResponse struct {
+	Greeting string `json:"greeting"`
+}
+
+func TestHandleJSON(t *testing.T) {
+	translations, err := i18n.GetTranslationFuncForDir(filepath.Join("..", translationsDir))
+	require.NoError(t, err)
+
+	api := &plugintest.API{}
+	api.On("GetUser", "user-id").Return(&model.User{Id: "user-id", Locale: "en"}, nil)
+	plugin := &Plugin{client: pluginapi.NewClient(api, &plugintest.Driver{}), translations: translations}
+	plugin.SetAPI(api)
+
+	router := mux.NewRouter()
+	handleJSON(plugin, router, http.MethodPost, "/teams/{team_id}/greet", RouteDoc{Summary: "Greet someone"}, func(r *Request, in *greetRequest) (*greetResponse, error) {
+		switch in.Name {
+		case "nobody":
+			return nil, model.NewAppError("greet", "api.error.not_authorized", nil, "", http.StatusForbidden)
+		case "boom":
+			return nil, errors.New("boom")
+		}
+		return &greetResponse{Greeting: "Hello " + in.Name + " from " + r.UserID + " in " + r.Var("team_id")}, nil
+	})
+	handleJSON(plugin, router, http.MethodDelete, "/greetings", RouteDoc{Summary: "Delete the greetings"}, func(r *Request, _ *Empty) (Empty, error) {
+		return Empty{}, nil
+	})
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Mattermost-User-ID", "user-id")
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	decodeError := func(t *testing.T, w *httptest.ResponseRecorder) *model.AppError {
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		var appErr model.AppError
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&appErr))
+		assert.Equal(t, w.Code, appErr.StatusCode)
+		return &appErr
+	}
+
+	t.Run("decodes the request and encodes the response", func(t *testing.T) {
+		w := serve(http.MethodPost, "/teams/team-id/greet", `{"name":"alice"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"greeting":"Hello alice from user-id in team-id"}`, w.Body.String())
+	})
+
+	t.Run("empty response", func(t *testing.T) {
+		w := serve(http.MethodDelete, "/greetings", "")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Empty(t, w.Body.String())
+	})
+
+	for name, tc := range map[string]struct {
+		body       string
+		statusCode int
+		id         string
+		message    string
+	}{
+		"missing body":     {body: "", statusCode: http.StatusBadRequest, id: "api.error.missing_body", message: "The request body is missing."},
+		"invalid body":     {body: `{"name":`, statusCode: http.StatusBadRequest, id: "api.error.invalid_body", message: "The request body is not valid JSON."},
+		"too large body":   {body: `{"name":"` + strings.Repeat("a", maxRequestBodySize) + `"}`, statusCode: http.StatusRequestEntityTooLarge, id: "api.error.body_too_large", message: "The request body must be at most 1048576 bytes."},
+		"invalid request":  {body: `{}`, statusCode: http.StatusBadRequest, id: "api.error.invalid_body", message: "The request body is not valid JSON."},
+		"handler AppError": {body: `{"name":"nobody"}`, statusCode: http.StatusForbidden, id: "api.error.not_authorized", message: "Not authorized"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := serve(http.MethodPost, "/teams/team-id/greet", tc.body)
+			require.Equal(t, tc.statusCode, w.Code)
+			appErr := decodeError(t, w)
+			assert.Equal(t, tc.id, appErr.Id)
+			assert.Equal(t, tc.message, appErr.Message)
+		})
+	}
+
+	t.Run("internal errors are logged without disclosing details", func(t *testing.T) {
+		api.On("LogError", "Failed to handle API request", "method", http.MethodPost, "path", "/teams/team-id/greet", "error", mock.Anything).Return().Once()
+
+		w := serve(http.MethodPost, "/teams/team-id/greet", `{"name":"boom"}`)
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		appErr := decodeError(t, w)
+		assert.Equal(t, "api.error.internal", appErr.Id)
+		assert.Empty(t, appErr.DetailedError)
+		api.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"path/filepath"
+
+	"github.com/mattermost/mattermost/server/public/shared/i18n"
+	"github.com/pkg/errors"
+)
+
+// translationsDir holds the server translations, one go-i18n file per locale, relative to the
+// plugin bundle.
+const translationsDir = "assets/i18n"
+
+// loadTranslations loads the server translations bundled with the plugin. Messages missing from
+// a locale fall back to English.
+func (p *Plugin) loadTranslations() (i18n.TranslationFuncByLocal, error) {
+	bundlePath, err := p.client.System.GetBundlePath()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get bundle path")
+	}
+
+	translations, err := i18n.GetTranslationFuncForDir(filepath.Join(bundlePath, translationsDir))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load translations")
+	}
+
+	return translations, nil
+}
+
+// requestTranslations returns the function translating the responses to the user who made the
+// request, falling back to English for anonymous requests or if the user cannot be loaded.
+func (p *Plugin) requestTranslations(r *http.Request) i18n.TranslateFunc {
+	if p.translations == nil {
+		return i18n.IdentityTfunc()
+	}
+
+	locale := ""
+	if userID := r.Header.Get("Mattermost-User-ID"); userID != "" {
+		if user, err := p.client.User.Get(userID); err == nil {
+			locale = user.Locale
+		}
+	}
+
+	return p.translations(locale)
+}
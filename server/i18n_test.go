@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// translationIDs returns the IDs of the messages translated by the Go sources below dir, by
+// position. Translation functions are called T, or called directly on the result of another
+// call, e.g. p.requestTranslations(r)("api.error.not_authorized"). The IDs of errors created
+// with model.NewAppError are translated too.
+func translationIDs(t *testing.T, dir string) map[string]string {
+	ids := map[string]string{}
+	fset := token.NewFileSet()
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return err
+		}
+
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+
+		ast.Inspect(file, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+			arg := call.Args[0]
+			switch fun := call.Fun.(type) {
+			case *ast.Ident:
+				if fun.Name != "T" {
+					return true
+				}
+			case *ast.SelectorExpr:
+				switch {
+				case fun.Sel.Name == "NewAppError" && len(call.Args) > 1:
+					arg = call.Args[1]
+				case fun.Sel.Name != "T":
+					return true
+				}
+			case *ast.CallExpr:
+			default:
+				return true
+			}
+
+			literal, ok := arg.(*ast.BasicLit)
+			if !ok || literal.Kind != token.STRING {
+				return true
+			}
+			id, err := strconv.Unquote(literal.Value)
+			if err == nil {
+				ids[fset.Position(literal.Pos()).String()] = id
+			}
+			return true
+		})
+		return nil
+	})
+	require.NoError(t, err)
+
+	return ids
+}
+
+// loadTranslationFile reads the translations of a go-i18n file by ID.
+func loadTranslationFile(t *testing.T, path string) map[string]string {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var messages []struct {
+		ID          string `json:"id"`
+		Translation string `json:"translation"`
+	}
+	require.NoError(t, json.Unmarshal(data, &messages), path)
+
+	translations := map[string]string{}
+	for _, message := range messages {
+		translations[message.ID] = message.Translation
+	}
+	return translations
+}
+
+func TestTranslations(t *testing.T) {
+	dir := filepath.Join("..", translationsDir)
+	english := loadTranslationFile(t, filepath.Join(dir, "en.json"))
+
+	t.Run("every message has an English translation", func(t *testing.T) {
+		ids := translationIDs(t, ".")
+		require.NotEmpty(t, ids)
+
+		for position, id := range ids {
+			assert.NotEmpty(t, english[id], "%s: missing English translation for %q in %s/en.json", position, id, translationsDir)
+		}
+	})
+
+	t.Run("other locales only translate English messages", func(t *testing.T) {
+		files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+		require.NoError(t, err)
+
+		for _, file := range files {
+			for id := range loadTranslationFile(t, file) {
+				_, ok := english[id]
+				assert.True(t, ok, "%s translates %q, which has no English translation", file, id)
+			}
+		}
+	})
+}
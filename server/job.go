@@ -1,6 +1,13 @@
 package main
 
+import "time"
+
+// runJob runs every minute on a single node of the cluster.
 func (p *Plugin) runJob() {
-	// Include job logic here
-	p.API.LogInfo("Job is currently running")
+	start := time.Now()
+	err := p.commandClient.RunScheduledCommands(start)
+	observeJob("scheduled_commands", start, err)
+	if err != nil {
+		p.API.LogError("Failed to run scheduled commands", "error", err)
+	}
 }
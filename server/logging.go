@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+)
+
+// requestIDHeader carries the ID correlating the log lines of a request, and is returned in the
+// response.
+const requestIDHeader = "X-Request-ID"
+
+// requestIDPattern matches the request IDs propagated from the request headers. Other IDs are
+// replaced, so that they cannot forge log lines.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// Logger logs through the plugin API, adding its key value pairs to every log line, e.g. the ID
+// of the request being handled.
+type Logger struct {
+	api           plugin.API
+	keyValuePairs []any
+}
+
+// With returns a logger adding the key value pairs to those of the logger.
+func (l *Logger) With(keyValuePairs ...any) *Logger {
+	return &Logger{
+		api:           l.api,
+		keyValuePairs: slices.Concat(l.keyValuePairs, keyValuePairs),
+	}
+}
+
+func (l *Logger) Debug(message string, keyValuePairs ...any) {
+	l.api.LogDebug(message, slices.Concat(keyValuePairs, l.keyValuePairs)...)
+}
+
+func (l *Logger) Info(message string, keyValuePairs ...any) {
+	l.api.LogInfo(message, slices.Concat(keyValuePairs, l.keyValuePairs)...)
+}
+
+func (l *Logger) Warn(message string, keyValuePairs ...any) {
+	l.api.LogWarn(message, slices.Concat(keyValuePairs, l.keyValuePairs)...)
+}
+
+func (l *Logger) Error(message string, keyValuePairs ...any) {
+	l.api.LogError(message, slices.Concat(keyValuePairs, l.keyValuePairs)...)
+}
+
+// requestContextKey is the key of the requestContext in the context of the requests.
+type requestContextKey struct{}
+
+// requestContext holds the request ID and logger of a request.
+type requestContext struct {
+	id     string
+	logger *Logger
+}
+
+// RequestLogging assigns an ID to every request, or propagates the one of the X-Request-ID
+// header, returning it in the same header of the response. Once the request is handled, it is
+// logged with its method, route, status, duration and user, and counted in the metrics.
+func (p *Plugin) RequestLogging(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := r.Header.Get(requestIDHeader)
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = model.NewId()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+
+		logger := (&Logger{api: p.API}).With("request_id", requestID)
+		r = r.WithContext(context.WithValue(r.Context(), requestContextKey{}, &requestContext{
+			id:     requestID,
+			logger: logger,
+		}))
+
+		recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		observeRequest(route, r.Method, recorder.statusCode, time.Since(start))
+
+		keyValuePairs := []any{
+			"method", r.Method,
+			"route", route,
+			"status", recorder.statusCode,
+			"duration", time.Since(start).String(),
+			"user_id", r.Header.Get("Mattermost-User-ID"),
+		}
+		if pluginID := r.Header.Get("Mattermost-Plugin-ID"); pluginID != "" {
+			keyValuePairs = append(keyValuePairs, "plugin_id", pluginID)
+		}
+		logger.Info("Handled API request", keyValuePairs...)
+	})
+}
+
+// requestID returns the ID of the request, or an empty string outside of RequestLogging.
+func requestID(r *http.Request) string {
+	if rc, ok := r.Context().Value(requestContextKey{}).(*requestContext); ok {
+		return rc.id
+	}
+	return ""
+}
+
+// requestLogger returns the logger of the request, adding the request ID to the log lines.
+// Outside of RequestLogging, it logs without it.
+func (p *Plugin) requestLogger(r *http.Request) *Logger {
+	if rc, ok := r.Context().Value(requestContextKey{}).(*requestContext); ok {
+		return rc.logger
+	}
+	return &Logger{api: p.API}
+}
+
+// statusRecorder records the status code of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(statusCode int) {
+	if !s.wroteHeader {
+		s.statusCode = statusCode
+		s.wroteHeader = true
+	}
+	s.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (s *statusRecorder) Write(data []byte) (int, error) {
+	s.wroteHeader = true
+	return s.ResponseWriter.Write(data)
+}
+
+// Unwrap returns the recorded ResponseWriter, for http.ResponseController.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
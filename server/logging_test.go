@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockRequestLogging allows the access log lines of RequestLogging, for users and plugins.
+func mockRequestLogging(api *plugintest.API) {
+	for _, count := range []int{12, 14} {
+		args := []any{"Handled API request"}
+		for range count {
+			args = append(args, mock.Anything)
+		}
+		api.On("LogInfo", args...).Return().Maybe()
+	}
+}
+
+func TestRequestLogging(t *testing.T) {
+	api := &plugintest.API{}
+	plugin := &Plugin{}
+	plugin.SetAPI(api)
+
+	router := mux.NewRouter()
+	router.Use(plugin.RequestLogging)
+	handleJSON(plugin, router, http.MethodGet, "/teams/{team_id}/greeting", RouteDoc{Summary: "Greet a team"}, func(r *Request, _ *Empty) (*HelloResponse, error) {
+		r.Logger.Debug("Greeting a team", "team_id", r.Var("team_id"))
+		return &HelloResponse{Message: "Hello, team!"}, nil
+	})
+	handleJSON(plugin, router, http.MethodGet, "/fail", RouteDoc{Summary: "Fail"}, func(r *Request, _ *Empty) (Empty, error) {
+		return Empty{}, model.NewAppError("fail", "api.error.forbidden", nil, "", http.StatusForbidden)
+	})
+
+	serve := func(path, requestID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("Mattermost-User-ID", "user-id")
+		if requestID != "" {
+			r.Header.Set(requestIDHeader, requestID)
+		}
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("propagates the request ID to the handler, the access log and the response", func(t *testing.T) {
+		api.On("LogDebug", "Greeting a team", "team_id", "team-id", "request_id", "request-id").Return().Once()
+		api.On("LogInfo", "Handled API request",
+			"method", http.MethodGet,
+			"route", "/teams/{team_id}/greeting",
+			"status", http.StatusOK,
+			"duration", mock.AnythingOfType("string"),
+			"user_id", "user-id",
+			"request_id", "request-id",
+		).Return().Once()
+
+		w := serve("/teams/team-id/greeting", "request-id")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "request-id", w.Header().Get(requestIDHeader))
+		api.AssertExpectations(t)
+	})
+
+	t.Run("assigns a request ID and returns it in errors", func(t *testing.T) {
+		api.On("LogInfo", "Handled API request",
+			"method", http.MethodGet,
+			"route", "/fail",
+			"status", http.StatusForbidden,
+			"duration", mock.AnythingOfType("string"),
+			"user_id", "user-id",
+			"request_id", mock.AnythingOfType("string"),
+		).Return().Once()
+
+		w := serve("/fail", "forged\nrequest-id")
+		require.Equal(t, http.StatusForbidden, w.Code)
+		requestID := w.Header().Get(requestIDHeader)
+		assert.True(t, model.IsValidId(requestID))
+
+		var appErr model.AppError
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&appErr))
+		assert.Equal(t, requestID, appErr.RequestId)
+		api.AssertExpectations(t)
+	})
+}
+
+func TestLogger(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("LogInfo", "Handled", "key", "value", "request_id", "request-id").Return().Once()
+	logger := (&Logger{api: api}).With("request_id", "request-id")
+
+	// The backing array of the caller's key value pairs has spare capacity, which the logger must
+	// not write into.
+	keyValuePairs := make([]any, 2, 4)
+	keyValuePairs[0], keyValuePairs[1] = "key", "value"
+	logger.Info("Handled", keyValuePairs...)
+
+	assert.Equal(t, []any{"key", "value", nil, nil}, keyValuePairs[:4])
+	api.AssertExpectations(t)
+}
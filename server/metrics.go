@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/mattermost/mattermost-plugin-starter-template/server/command"
+	"github.com/mattermost/mattermost-plugin-starter-template/server/metrics"
+)
+
+var (
+	httpRequests       = metrics.Default.NewCounterVec("http_requests_total", "HTTP requests, by route, method and status.", "route", "method", "status")
+	httpDurations      = metrics.Default.NewHistogramVec("http_request_duration_seconds", "Duration of the HTTP requests, in seconds.", metrics.DefaultBuckets, "route", "method")
+	httpPanics         = metrics.Default.NewCounterVec("http_panics_total", "Panics the API handlers recovered from.")
+	commandInvocations = metrics.Default.NewCounterVec("command_invocations_total", "Slash command invocations, by trigger and result.", "trigger", "result")
+	commandDurations   = metrics.Default.NewHistogramVec("command_duration_seconds", "Duration of the slash command invocations, in seconds.", metrics.DefaultBuckets, "trigger")
+	jobRuns            = metrics.Default.NewCounterVec("job_runs_total", "Background job runs, by job.", "job")
+	jobFailures        = metrics.Default.NewCounterVec("job_failures_total", "Failed background job runs, by job.", "job")
+	jobDurations       = metrics.Default.NewHistogramVec("job_duration_seconds", "Duration of the background job runs, in seconds.", metrics.DefaultBuckets, "job")
+)
+
+// observeRequest records a handled HTTP request.
+func observeRequest(route, method string, status int, duration time.Duration) {
+	httpRequests.Inc(route, method, strconv.Itoa(status))
+	httpDurations.Observe(duration.Seconds(), route, method)
+}
+
+// observeCommand records a slash command invocation, see command.Handler.Observe.
+func observeCommand(_ *model.CommandArgs, trigger string, duration time.Duration, outcome command.Outcome) {
+	commandInvocations.Inc(trigger, string(outcome))
+	commandDurations.Observe(duration.Seconds(), trigger)
+}
+
+// observeJob records a run of a background job that started at the given time.
+func observeJob(job string, start time.Time, err error) {
+	jobRuns.Inc(job)
+	if err != nil {
+		jobFailures.Inc(job)
+	}
+	jobDurations.Observe(time.Since(start).Seconds(), job)
+}
+
+// Metrics serves the metrics of the plugin in the Prometheus text format.
+func (p *Plugin) Metrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", metrics.ContentType)
+	if _, err := metrics.Default.WriteTo(w); err != nil {
+		p.requestLogger(r).Error("Failed to write metrics", "error", err)
+	}
+}
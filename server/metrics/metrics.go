@@ -0,0 +1,330 @@
+// Package metrics collects the metrics of the plugin and exposes them in the Prometheus text
+// format.
+//
+// Packages declare their metrics once, usually as package variables registered with Default:
+//
+//	var jobRuns = metrics.Default.NewCounterVec("job_runs_total", "Background job runs.", "job")
+//
+//	jobRuns.Inc("scheduled_commands")
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds of the histogram buckets of durations, in seconds.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Default is the registry of the metrics of the plugin, served by the metrics endpoint.
+var Default = NewRegistry("starter_template")
+
+var (
+	// namePattern matches the valid metric names.
+	namePattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+	// labelPattern matches the valid label names.
+	labelPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+	// labelEscaper escapes label values.
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+	// helpEscaper escapes help texts.
+	helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+// Registry holds metrics and writes them in the Prometheus text format.
+type Registry struct {
+	// namespace prefixes the names of the metrics, e.g. `starter_template` for
+	// `starter_template_job_runs_total`.
+	namespace string
+
+	// lock synchronizes access to metrics.
+	lock sync.RWMutex
+
+	// metrics are the registered metrics, in the order they were registered.
+	metrics []metric
+}
+
+// metric is a family of series of the same name and type.
+type metric interface {
+	// name is the name of the metric, without the namespace.
+	name() string
+
+	// write writes the series of the metric, named with the given full name.
+	write(b *strings.Builder, fullName string)
+}
+
+// NewRegistry creates an empty registry prefixing the names of its metrics with the namespace.
+func NewRegistry(namespace string) *Registry {
+	return &Registry{namespace: namespace}
+}
+
+// register adds the metric to the registry. It panics if the name or labels are invalid, or if
+// the name is taken, as metrics are declared once when the plugin starts.
+func (r *Registry) register(m metric, labels []string) {
+	if !namePattern.MatchString(m.name()) {
+		panic(fmt.Sprintf("invalid metric name %q", m.name()))
+	}
+	for _, label := range labels {
+		if !labelPattern.MatchString(label) || strings.HasPrefix(label, "__") {
+			panic(fmt.Sprintf("invalid label %q of metric %q", label, m.name()))
+		}
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, registered := range r.metrics {
+		if registered.name() == m.name() {
+			panic(fmt.Sprintf("metric %q is registered twice", m.name()))
+		}
+	}
+	r.metrics = append(r.metrics, m)
+}
+
+// WriteTo writes the metrics in the Prometheus text format, version 0.0.4.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.lock.RLock()
+	metrics := slices.Clone(r.metrics)
+	r.lock.RUnlock()
+
+	var b strings.Builder
+	for _, m := range metrics {
+		fullName := m.name()
+		if r.namespace != "" {
+			fullName = r.namespace + "_" + fullName
+		}
+		m.write(&b, fullName)
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// ContentType is the content type of the Prometheus text format written by WriteTo.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// desc describes a metric.
+type desc struct {
+	metricName string
+	help       string
+	labels     []string
+}
+
+func (d *desc) name() string {
+	return d.metricName
+}
+
+// writeHeader writes the help and type of the metric.
+func (d *desc) writeHeader(b *strings.Builder, fullName, metricType string) {
+	fmt.Fprintf(b, "# HELP %s %s\n", fullName, helpEscaper.Replace(d.help))
+	fmt.Fprintf(b, "# TYPE %s %s\n", fullName, metricType)
+}
+
+// key identifies the series with the label values, checking that there is one per label.
+func (d *desc) key(labelValues []string) string {
+	if len(labelValues) != len(d.labels) {
+		panic(fmt.Sprintf("metric %q has %d labels, got %d values", d.metricName, len(d.labels), len(labelValues)))
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+// formatLabels formats the label pairs of a series, followed by the extra pairs.
+func (d *desc) formatLabels(labelValues []string, extra ...string) string {
+	pairs := make([]string, 0, len(labelValues)+len(extra)/2)
+	for i, value := range labelValues {
+		pairs = append(pairs, d.labels[i]+`="`+labelEscaper.Replace(value)+`"`)
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+labelEscaper.Replace(extra[i+1])+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// formatValue formats a sample value.
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// sortedKeys returns the keys of the series, sorted so that the output is stable.
+func sortedKeys[V any](series map[string]V) []string {
+	keys := make([]string, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// CounterVec is a counter partitioned by labels, e.g. requests by route and status.
+type CounterVec struct {
+	desc
+
+	lock   sync.Mutex
+	series map[string]*counterSeries
+}
+
+type counterSeries struct {
+	labelValues []string
+	value       float64
+}
+
+// NewCounterVec registers a counter with the given labels. A counter without labels starts at
+// zero, the others have no series until they are incremented.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		desc:   desc{metricName: name, help: help, labels: labels},
+		series: map[string]*counterSeries{},
+	}
+	r.register(c, labels)
+	if len(labels) == 0 {
+		c.Add(0)
+	}
+	return c
+}
+
+// Inc increments the counter with the label values, given in the order of the labels.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds the value, which must not be negative, to the counter with the label values.
+func (c *CounterVec) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		panic(fmt.Sprintf("counter %q cannot decrease", c.metricName))
+	}
+	key := c.key(labelValues)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	series, ok := c.series[key]
+	if !ok {
+		series = &counterSeries{labelValues: slices.Clone(labelValues)}
+		c.series[key] = series
+	}
+	series.value += value
+}
+
+// Value returns the value of the counter with the label values.
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	key := c.key(labelValues)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if series, ok := c.series[key]; ok {
+		return series.value
+	}
+	return 0
+}
+
+func (c *CounterVec) write(b *strings.Builder, fullName string) {
+	c.writeHeader(b, fullName, "counter")
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, key := range sortedKeys(c.series) {
+		series := c.series[key]
+		fmt.Fprintf(b, "%s%s %s\n", fullName, c.formatLabels(series.labelValues), formatValue(series.value))
+	}
+}
+
+// HistogramVec is a histogram partitioned by labels, e.g. durations by route.
+type HistogramVec struct {
+	desc
+	buckets []float64
+
+	lock   sync.Mutex
+	series map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labelValues []string
+
+	// counts are the observations of every bucket, not cumulated.
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewHistogramVec registers a histogram with the given bucket upper bounds, in increasing order,
+// and labels.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if !slices.IsSorted(buckets) {
+		panic(fmt.Sprintf("buckets of histogram %q are not sorted", name))
+	}
+	if slices.Contains(labels, "le") {
+		panic(fmt.Sprintf("histogram %q cannot have a label le", name))
+	}
+	h := &HistogramVec{
+		desc:    desc{metricName: name, help: help, labels: labels},
+		buckets: slices.Clone(buckets),
+		series:  map[string]*histogramSeries{},
+	}
+	r.register(h, labels)
+	return h
+}
+
+// Observe records a value, e.g. a duration in seconds, in the histogram with the label values.
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	key := h.key(labelValues)
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	series, ok := h.series[key]
+	if !ok {
+		series = &histogramSeries{labelValues: slices.Clone(labelValues), counts: make([]uint64, len(h.buckets))}
+		h.series[key] = series
+	}
+	if i, _ := slices.BinarySearch(h.buckets, value); i < len(h.buckets) {
+		series.counts[i]++
+	}
+	series.count++
+	series.sum += value
+}
+
+// Count returns how many values the histogram with the label values observed.
+func (h *HistogramVec) Count(labelValues ...string) uint64 {
+	key := h.key(labelValues)
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if series, ok := h.series[key]; ok {
+		return series.count
+	}
+	return 0
+}
+
+func (h *HistogramVec) write(b *strings.Builder, fullName string) {
+	h.writeHeader(b, fullName, "histogram")
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for _, key := range sortedKeys(h.series) {
+		series := h.series[key]
+		var cumulative uint64
+		for i, upperBound := range h.buckets {
+			cumulative += series.counts[i]
+			fmt.Fprintf(b, "%s_bucket%s %d\n", fullName, h.formatLabels(series.labelValues, "le", formatValue(upperBound)), cumulative)
+		}
+		fmt.Fprintf(b, "%s_bucket%s %d\n", fullName, h.formatLabels(series.labelValues, "le", "+Inf"), series.count)
+		fmt.Fprintf(b, "%s_sum%s %s\n", fullName, h.formatLabels(series.labelValues), formatValue(series.sum))
+		fmt.Fprintf(b, "%s_count%s %d\n", fullName, h.formatLabels(series.labelValues), series.count)
+	}
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry("test")
+	panics := registry.NewCounterVec("panics_total", "Recovered panics.")
+	requests := registry.NewCounterVec("requests_total", "HTTP requests,\nby route and status.", "route", "status")
+	durations := registry.NewHistogramVec("request_duration_seconds", "Duration of the requests.", []float64{0.1, 1}, "route")
+
+	requests.Inc("/hello", "200")
+	requests.Inc("/hello", "200")
+	requests.Add(3, `/say "hi"\now`, "500")
+	durations.Observe(0.05, "/hello")
+	durations.Observe(0.1, "/hello")
+	durations.Observe(2.5, "/hello")
+
+	assert.Equal(t, float64(2), requests.Value("/hello", "200"))
+	assert.Equal(t, float64(0), requests.Value("/other", "200"))
+	assert.Equal(t, uint64(3), durations.Count("/hello"))
+	assert.Equal(t, float64(0), panics.Value())
+
+	var b strings.Builder
+	_, err := registry.WriteTo(&b)
+	require.NoError(t, err)
+	assert.Equal(t, `# HELP test_panics_total Recovered panics.
+# TYPE test_panics_total counter
+test_panics_total 0
+# HELP test_requests_total HTTP requests,\nby route and status.
+# TYPE test_requests_total counter
+test_requests_total{route="/hello",status="200"} 2
+test_requests_total{route="/say \"hi\"\\now",status="500"} 3
+# HELP test_request_duration_seconds Duration of the requests.
+# TYPE test_request_duration_seconds histogram
+test_request_duration_seconds_bucket{route="/hello",le="0.1"} 2
+test_request_duration_seconds_bucket{route="/hello",le="1"} 2
+test_request_duration_seconds_bucket{route="/hello",le="+Inf"} 3
+test_request_duration_seconds_sum{route="/hello"} 2.65
+test_request_duration_seconds_count{route="/hello"} 3
+`, b.String())
+}
+
+func TestRegistryRejectsInvalidMetrics(t *testing.T) {
+	registry := NewRegistry("")
+	registry.NewCounterVec("taken_total", "Taken.")
+
+	assert.PanicsWithValue(t, `metric "taken_total" is registered twice`, func() { registry.NewCounterVec("taken_total", "Taken.") })
+	assert.Panics(t, func() { registry.NewCounterVec("invalid-name", "Invalid.") })
+	assert.Panics(t, func() { registry.NewCounterVec("labels_total", "Invalid label.", "in-valid") })
+	assert.Panics(t, func() { registry.NewHistogramVec("le_seconds", "Reserved label.", DefaultBuckets, "le") })
+	assert.Panics(t, func() { registry.NewHistogramVec("unsorted_seconds", "Unsorted.", []float64{1, 0.1}) })
+
+	counter := registry.NewCounterVec("counter_total", "Counter.", "label")
+	assert.Panics(t, func() { counter.Inc() }, "label values are missing")
+	assert.Panics(t, func() { counter.Add(-1, "value") }, "counters cannot decrease")
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-starter-template/server/command"
+	"github.com/mattermost/mattermost-plugin-starter-template/server/metrics"
+	"github.com/mattermost/mattermost-plugin-starter-template/server/store/kvstore"
+)
+
+func TestMetrics(t *testing.T) {
+	api := &plugintest.API{}
+	mockKVStore(api)
+	mockRequestLogging(api)
+	api.On("HasPermissionTo", "admin-id", model.PermissionManageSystem).Return(true)
+	api.On("HasPermissionTo", "user-id", model.PermissionManageSystem).Return(false)
+	api.On("GetTeamMember", "team-id", "user-id").Return(&model.TeamMember{TeamId: "team-id", UserId: "user-id"}, nil)
+	api.On("LogWarn", "Request not permitted", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+
+	client := pluginapi.NewClient(api, &plugintest.Driver{})
+	plugin := &Plugin{client: client, kvstore: kvstore.NewKVStore(client)}
+	plugin.SetAPI(api)
+	plugin.router = plugin.initRouter()
+
+	serve := func(path, userID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("Mattermost-User-ID", userID)
+		plugin.ServeHTTP(nil, w, r)
+		return w
+	}
+
+	requests := httpRequests.Value("/api/v1/teams/{team_id}/aliases", http.MethodGet, "200")
+	durations := httpDurations.Count("/api/v1/teams/{team_id}/aliases", http.MethodGet)
+	require.Equal(t, http.StatusOK, serve("/api/v1/teams/team-id/aliases", "user-id").Code)
+	assert.Equal(t, requests+1, httpRequests.Value("/api/v1/teams/{team_id}/aliases", http.MethodGet, "200"))
+	assert.Equal(t, durations+1, httpDurations.Count("/api/v1/teams/{team_id}/aliases", http.MethodGet))
+
+	w := serve("/api/v1/metrics", "admin-id")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, metrics.ContentType, w.Header().Get("Content-Type"))
+	for _, line := range []string{
+		"# TYPE starter_template_http_requests_total counter",
+		`starter_template_http_requests_total{route="/api/v1/teams/{team_id}/aliases",method="GET",status="200"} `,
+		"# TYPE starter_template_http_request_duration_seconds histogram",
+		"# TYPE starter_template_http_panics_total counter",
+		"# TYPE starter_template_command_invocations_total counter",
+		"# TYPE starter_template_job_runs_total counter",
+		`starter_template_kv_operations_total{operation="get",result="success"} `,
+		`starter_template_kv_operation_duration_seconds_count{operation="get"} `,
+	} {
+		assert.Contains(t, w.Body.String(), line)
+	}
+
+	assert.Equal(t, http.StatusForbidden, serve("/api/v1/metrics", "user-id").Code)
+}
+
+func TestObserve(t *testing.T) {
+	invocations := commandInvocations.Value("observed", "error")
+	observeCommand(&model.CommandArgs{}, "observed", time.Millisecond, command.OutcomeError)
+	assert.Equal(t, invocations+1, commandInvocations.Value("observed", "error"))
+	assert.Equal(t, uint64(1), commandDurations.Count("observed"))
+
+	observeJob("observed", time.Now(), nil)
+	observeJob("observed", time.Now(), errors.New("failed"))
+	assert.Equal(t, float64(2), jobRuns.Value("observed"))
+	assert.Equal(t, float64(1), jobFailures.Value("observed"))
+	assert.Equal(t, uint64(2), jobDurations.Count("observed"))
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+// openAPIVersion is the version of the OpenAPI specification the generated document follows.
+const openAPIVersion = "3.0.3"
+
+// The security schemes of the routes: users are authenticated by their session, other plugins
+// are identified by the server and incoming webhooks by the signature of their payloads. Public
+// routes do not require any.
+const (
+	securitySession = "session"
+	securityPlugin  = "plugin"
+	securityWebhook = "webhook"
+	securityPublic  = "public"
+)
+
+// pathVariablePattern matches the variables of mux path templates, e.g. `{team_id}` or
+// `{id:[a-z0-9]+}`.
+var pathVariablePattern = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// RouteDoc documents an API route in the OpenAPI document served at /api/v1/openapi.json. Every
+// route must be documented.
+type RouteDoc struct {
+	// Summary is a short summary of what the route does.
+	Summary string
+
+	// Description optionally explains the route in more detail, in CommonMark.
+	Description string
+
+	// Tags group related routes.
+	Tags []string
+
+	// Query describes the query parameters of the route, by name.
+	Query map[string]string
+
+	// Security is the security scheme of the route, securitySession if empty.
+	Security string
+
+	// Request and Response are values of the types of the request and response bodies, for
+	// routes not registered with handleJSON. They are nil for routes without a body.
+	Request  any
+	Response any
+}
+
+// documentedRoute is a route registered with its documentation.
+type documentedRoute struct {
+	route *mux.Route
+	doc   RouteDoc
+
+	// request and response are the types of the bodies, or nil if there is none.
+	request  reflect.Type
+	response reflect.Type
+}
+
+// handle registers a handler for the method and path on the router, with its documentation.
+// Prefer handleJSON for JSON APIs.
+func (p *Plugin) handle(router *mux.Router, method, path string, doc RouteDoc, handler http.HandlerFunc) *mux.Route {
+	route := router.Handle(path, handler).Methods(method)
+	p.document(route, doc, bodyType(doc.Request), bodyType(doc.Response))
+	return route
+}
+
+// document records the documentation of a route.
+func (p *Plugin) document(route *mux.Route, doc RouteDoc, request, response reflect.Type) {
+	p.routes = append(p.routes, &documentedRoute{
+		route:    route,
+		doc:      doc,
+		request:  request,
+		response: response,
+	})
+}
+
+// bodyType returns the type of the body value, or nil if there is no body.
+func bodyType(v any) reflect.Type {
+	if v == nil {
+		return nil
+	}
+	t := reflect.TypeOf(v)
+	if t == reflect.TypeOf(Empty{}) {
+		return nil
+	}
+	return t
+}
+
+// OpenAPI serves the OpenAPI document describing the documented routes of the plugin API.
+func (p *Plugin) OpenAPI(r *Request, _ *Empty) (map[string]any, error) {
+	manifest, err := p.client.System.GetManifest()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get plugin manifest")
+	}
+
+	return p.openAPIDocument(manifest)
+}
+
+// openAPIDocument generates the OpenAPI document of the documented routes.
+func (p *Plugin) openAPIDocument(manifest *model.Manifest) (map[string]any, error) {
+	schemas := newSchemaRegistry()
+	errorSchema := schemas.schema(reflect.TypeOf(model.AppError{}))
+
+	paths := map[string]any{}
+	for _, documented := range p.routes {
+		template, err := documented.route.GetPathTemplate()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get route path")
+		}
+		methods, err := documented.route.GetMethods()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get methods of route %s", template)
+		}
+
+		operation := documented.operation(template, schemas, errorSchema)
+
+		openAPIPath := pathVariablePattern.ReplaceAllString(template, "{$1}")
+		item, _ := paths[openAPIPath].(map[string]any)
+		if item == nil {
+			item = map[string]any{}
+			paths[openAPIPath] = item
+		}
+		for _, method := range methods {
+			item[strings.ToLower(method)] = operation
+		}
+	}
+
+	return map[string]any{
+		"openapi": openAPIVersion,
+		"info": map[string]any{
+			"title":       manifest.Name,
+			"description": manifest.Description,
+			"version":     manifest.Version,
+		},
+		"servers": []any{
+			map[string]any{"url": path.Join("/plugins", manifest.Id)},
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas.schemas,
+			"securitySchemes": map[string]any{
+				securitySession: map[string]any{"type": "http", "scheme": "bearer"},
+				securityPlugin: map[string]any{
+					"type":        "apiKey",
+					"in":          "header",
+					"name":        "Mattermost-Plugin-ID",
+					"description": "Set by the server for the requests of other plugins made with PluginHTTP.",
+				},
+				securityWebhook: map[string]any{
+					"type":        "apiKey",
+					"in":          "header",
+					"name":        "X-Webhook-Signature",
+					"description": "The signature of the payload with the secret of the webhook.",
+				},
+			},
+		},
+		"security": []any{
+			map[string]any{securitySession: []any{}},
+		},
+	}, nil
+}
+
+// operation generates the OpenAPI operation of the route with the given path template.
+func (d *documentedRoute) operation(template string, schemas *schemaRegistry, errorSchema map[string]any) map[string]any {
+	operation := map[string]any{
+		"summary": d.doc.Summary,
+	}
+	if d.doc.Description != "" {
+		operation["description"] = d.doc.Description
+	}
+	if len(d.doc.Tags) > 0 {
+		operation["tags"] = d.doc.Tags
+	}
+	switch d.doc.Security {
+	case "", securitySession:
+	case securityPublic:
+		operation["security"] = []any{}
+	default:
+		operation["security"] = []any{map[string]any{d.doc.Security: []any{}}}
+	}
+
+	parameters := []any{}
+	for _, match := range pathVariablePattern.FindAllStringSubmatch(template, -1) {
+		parameters = append(parameters, map[string]any{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]any{"type": "string"},
+		})
+	}
+	for _, name := range slices.Sorted(maps.Keys(d.doc.Query)) {
+		parameters = append(parameters, map[string]any{
+			"name":        name,
+			"in":          "query",
+			"description": d.doc.Query[name],
+			"schema":      map[string]any{"type": "string"},
+		})
+	}
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+
+	if d.request != nil {
+		operation["requestBody"] = map[string]any{
+			"required": true,
+			"content":  jsonContent(schemas.schema(d.request)),
+		}
+	}
+
+	responses := map[string]any{
+		"default": map[string]any{
+			"description": "Error",
+			"content":     jsonContent(errorSchema),
+		},
+	}
+	if d.response != nil {
+		responses["200"] = map[string]any{
+			"description": "OK",
+			"content":     jsonContent(schemas.schema(d.response)),
+		}
+	} else {
+		responses["204"] = map[string]any{"description": "No Content"}
+	}
+	operation["responses"] = responses
+
+	return operation
+}
+
+// jsonContent describes a JSON body with the given schema.
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{
+		"application/json": map[string]any{"schema": schema},
+	}
+}
+
+// schemaRegistry generates the schemas of Go types, collecting named struct types as components.
+type schemaRegistry struct {
+	// schemas are the component schemas, by name.
+	schemas map[string]any
+
+	// names are the component names of the registered types.
+	names map[reflect.Type]string
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{
+		schemas: map[string]any{},
+		names:   map[reflect.Type]string{},
+	}
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schema returns the schema of the type as encoded by encoding/json. Named struct types are
+// referenced from the components.
+func (s *schemaRegistry) schema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == rawMessageType:
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + s.register(t)}
+	default:
+		return map[string]any{}
+	}
+}
+
+// register adds the schema of the named struct type to the components, returning its name.
+func (s *schemaRegistry) register(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	if _, taken := s.schemas[name]; taken {
+		name = path.Base(t.PkgPath()) + "." + name
+	}
+
+	// Register the name before generating the schema, for recursive types.
+	s.names[t] = name
+	s.schemas[name] = nil
+	s.schemas[name] = s.structSchema(t)
+
+	return name
+}
+
+// structSchema returns the schema of the fields of the struct type, as encoded by encoding/json.
+func (s *schemaRegistry) structSchema(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	s.addProperties(properties, t)
+	return map[string]any{"type": "object", "properties": properties}
+}
+
+// addProperties adds the schemas of the encoded fields of the struct type to properties, including
+// those of embedded structs.
+func (s *schemaRegistry) addProperties(properties map[string]any, t reflect.Type) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				s.addProperties(properties, embedded)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = s.schema(field.Type)
+	}
+}
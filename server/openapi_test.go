@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutesAreDocumented(t *testing.T) {
+	plugin := &Plugin{}
+	router := plugin.initRouter()
+
+	documented := map[*mux.Route]RouteDoc{}
+	for _, route := range plugin.routes {
+		documented[route.route] = route.doc
+	}
+
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		if route.GetHandler() == nil {
+			return nil
+		}
+		template, err := route.GetPathTemplate()
+		require.NoError(t, err)
+
+		doc, ok := documented[route]
+		if assert.True(t, ok, "route %s is not documented, register it with handleJSON or Plugin.handle", template) {
+			assert.NotEmpty(t, doc.Summary, "route %s has no summary", template)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestOpenAPI(t *testing.T) {
+	bundlePath, err := filepath.Abs("..")
+	require.NoError(t, err)
+
+	api := &plugintest.API{}
+	api.On("GetBundlePath").Return(bundlePath, nil)
+	plugin := &Plugin{client: pluginapi.NewClient(api, &plugintest.Driver{})}
+	mockRequestLogging(api)
+	plugin.SetAPI(api)
+	plugin.router = plugin.initRouter()
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil)
+	r.Header.Set("Mattermost-User-ID", "user-id")
+	plugin.ServeHTTP(nil, w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var document struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct {
+			Title string `json:"title"`
+		} `json:"info"`
+		Servers []struct {
+			URL string `json:"url"`
+		} `json:"servers"`
+		Paths      map[string]map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&document))
+
+	assert.Equal(t, openAPIVersion, document.OpenAPI)
+	assert.Equal(t, "Plugin Starter Template", document.Info.Title)
+	assert.Equal(t, "/plugins/com.mattermost.plugin-starter-template", document.Servers[0].URL)
+
+	operations := 0
+	for _, item := range document.Paths {
+		operations += len(item)
+	}
+	require.Equal(t, len(plugin.routes), operations)
+	hello := document.Paths["/api/v1/hello"]["get"]
+	require.NotNil(t, hello)
+	assert.Equal(t, "Greet the world", hello["summary"])
+	assert.Equal(t, "#/components/schemas/HelloResponse", hello["responses"].(map[string]any)["200"].(map[string]any)["content"].(map[string]any)["application/json"].(map[string]any)["schema"].(map[string]any)["$ref"])
+	assert.Equal(t, map[string]any{"type": "object", "properties": map[string]any{"message": map[string]any{"type": "string"}}}, document.Components.Schemas["HelloResponse"])
+
+	autocomplete := document.Paths["/api/v1/autocomplete/{command}/{argument}"]["get"]
+	require.NotNil(t, autocomplete)
+	var parameters []string
+	for _, parameter := range autocomplete["parameters"].([]any) {
+		parameter := parameter.(map[string]any)
+		parameters = append(parameters, parameter["in"].(string)+":"+parameter["name"].(string))
+	}
+	assert.Equal(t, []string{"path:command", "path:argument", "query:channel_id", "query:root_id", "query:team_id", "query:user_input"}, parameters)
+
+	assert.NotContains(t, hello, "security")
+	assert.Equal(t, []any{map[string]any{"plugin": []any{}}}, document.Paths["/plugin/v1/hello"]["get"]["security"])
+	assert.Equal(t, []any{}, document.Paths["/assets/{path}"]["get"]["security"])
+
+	assert.Contains(t, document.Paths["/api/v1/dialog"]["post"], "requestBody")
+	assert.Contains(t, document.Components.Schemas, "SubmitDialogRequest")
+	assert.Contains(t, document.Components.Schemas, "AppError")
+}
+
+func TestSchema(t *testing.T) {
+	type node struct {
+		Name     string            `json:"name"`
+		Children []*node           `json:"children,omitempty"`
+		Labels   map[string]string `json:"labels"`
+		Secret   string            `json:"-"`
+		Data     []byte
+	}
+
+	schemas := newSchemaRegistry()
+	assert.Equal(t, map[string]any{"type": "array", "items": map[string]any{"$ref": "#/components/schemas/node"}}, schemas.schema(reflect.TypeOf([]node{})))
+	assert.Equal(t, map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"name":     map[string]any{"type": "string"},
+			"children": map[string]any{"type": "array", "items": map[string]any{"$ref": "#/components/schemas/node"}},
+			"labels":   map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "string"}},
+			"Data":     map[string]any{"type": "string", "format": "byte"},
+		},
+	}, schemas.schemas["node"])
+}
@@ -10,9 +10,11 @@ import (
 	"github.com/mattermost/mattermost/server/public/plugin"
 	"github.com/mattermost/mattermost/server/public/pluginapi"
 	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
+	"github.com/mattermost/mattermost/server/public/shared/i18n"
 	"github.com/pkg/errors"
 
 	"github.com/mattermost/mattermost-plugin-starter-template/server/command"
+	"github.com/mattermost/mattermost-plugin-starter-template/server/ratelimit"
 	"github.com/mattermost/mattermost-plugin-starter-template/server/store/kvstore"
 )
 
@@ -26,12 +28,29 @@ type Plugin struct {
 	// client is the Mattermost server API client.
 	client *pluginapi.Client
 
+	// botUserID is the user ID of the bot the plugin posts as.
+	botUserID string
+
+	// translations translate the messages of the plugin into the locale of the user.
+	translations i18n.TranslationFuncByLocal
+
 	// commandClient is the client used to register and execute slash commands.
 	commandClient command.Command
 
+	// rateLimiter limits the rate of the API requests and slash commands of every user.
+	rateLimiter *ratelimit.Limiter
+
 	// router is the HTTP router for handling API requests.
 	router *mux.Router
 
+	// routes are the documented routes of the router.
+	routes []*documentedRoute
+
+	// panics counts the panics the API handlers recovered from.
+	panics panicCounter
+
+	// backgroundJob runs every minute on a single node of the cluster, e.g. to run the
+	// scheduled commands.
 	backgroundJob *cluster.Job
 
 	// configurationLock synchronizes access to the configuration.
@@ -48,14 +67,44 @@ func (p *Plugin) OnActivate() error {
 
 	p.kvstore = kvstore.NewKVStore(p.client)
 
-	p.commandClient = command.NewCommandHandler(p.client)
+	botUserID, err := p.client.Bot.EnsureBot(&model.Bot{
+		Username:    "starter-template",
+		DisplayName: "Starter Template",
+		Description: "Created by the Plugin Starter Template.",
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to ensure bot")
+	}
+	p.botUserID = botUserID
+
+	manifest, err := p.client.System.GetManifest()
+	if err != nil {
+		return errors.Wrap(err, "failed to get plugin manifest")
+	}
+
+	translations, err := p.loadTranslations()
+	if err != nil {
+		return err
+	}
+	p.translations = translations
+
+	rateLimits, err := p.getConfiguration().rateLimits()
+	if err != nil {
+		return err
+	}
+	p.rateLimiter = ratelimit.NewLimiter(p.kvstore, p.client.Log)
+	p.rateLimiter.Configure(rateLimits)
+
+	p.commandClient = command.NewCommandHandler(p.client, p.kvstore, manifest.Id, p.botUserID, p.translations, p.getConfiguration().commandConfig())
+	p.commandClient.LimitRate(p.rateLimiter)
+	p.commandClient.Observe(observeCommand)
 
 	p.router = p.initRouter()
 
 	job, err := cluster.Schedule(
 		p.API,
 		"BackgroundJob",
-		cluster.MakeWaitForRoundedInterval(1*time.Hour),
+		cluster.MakeWaitForRoundedInterval(1*time.Minute),
 		p.runJob,
 	)
 	if err != nil {
@@ -74,6 +123,11 @@ func (p *Plugin) OnDeactivate() error {
 			p.API.LogError("Failed to close background job", "err", err)
 		}
 	}
+	if p.commandClient != nil {
+		if err := p.commandClient.Close(); err != nil {
+			p.API.LogError("Failed to close command handler", "err", err)
+		}
+	}
 	return nil
 }
 
@@ -1,17 +1,34 @@
 package main
 
 import (
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
 	"testing"
 
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/mattermost/mattermost-plugin-starter-template/server/command"
+	"github.com/mattermost/mattermost-plugin-starter-template/server/command/mocks"
+	"github.com/mattermost/mattermost-plugin-starter-template/server/store/kvstore"
 )
 
 func TestServeHTTP(t *testing.T) {
 	assert := assert.New(t)
 	plugin := Plugin{}
+	api := &plugintest.API{}
+	mockRequestLogging(api)
+	plugin.SetAPI(api)
 	plugin.router = plugin.initRouter()
 	w := httptest.NewRecorder()
 	r := httptest.NewRequest(http.MethodGet, "/api/v1/hello", nil)
@@ -22,9 +39,155 @@ func TestServeHTTP(t *testing.T) {
 	result := w.Result()
 	assert.NotNil(result)
 	defer func() { _ = result.Body.Close() }()
-	bodyBytes, err := io.ReadAll(result.Body)
-	assert.Nil(err)
-	bodyString := string(bodyBytes)
+	assert.Equal("application/json", result.Header.Get("Content-Type"))
+
+	var response HelloResponse
+	assert.Nil(json.NewDecoder(result.Body).Decode(&response))
+	assert.Equal("Hello, world!", response.Message)
+}
+
+func TestAutocompleteSuggestions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	commandClient := mocks.NewMockCommand(ctrl)
+
+	plugin := Plugin{commandClient: commandClient}
+	api := &plugintest.API{}
+	mockRequestLogging(api)
+	plugin.SetAPI(api)
+	plugin.router = plugin.initRouter()
+
+	items := []model.AutocompleteListItem{{Item: "greeting", HelpText: "Your greeting"}}
+	commandClient.EXPECT().
+		Suggest(&model.CommandArgs{UserId: "test-user-id", ChannelId: "channel-id", TeamId: "team-id"}, "hello alias remove", "name", "/hello alias remove gr").
+		Return(items, nil)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/autocomplete/hello/alias/remove/name?user_input=%2Fhello+alias+remove+gr&channel_id=channel-id&team_id=team-id", nil)
+	r.Header.Set("Mattermost-User-ID", "test-user-id")
+
+	plugin.ServeHTTP(nil, w, r)
+
+	result := w.Result()
+	defer func() { _ = result.Body.Close() }()
+	require.Equal(t, http.StatusOK, result.StatusCode)
+
+	var actual []model.AutocompleteListItem
+	require.NoError(t, json.NewDecoder(result.Body).Decode(&actual))
+	assert.Equal(t, items, actual)
+}
+
+func TestAutocompleteFetchURLs(t *testing.T) {
+	api := &plugintest.API{}
+	mockKVStore(api)
+	mockRequestLogging(api)
+	var registered *model.Command
+	api.On("RegisterCommand", mock.AnythingOfType("*model.Command")).Run(func(args mock.Arguments) {
+		registered = args.Get(0).(*model.Command)
+	}).Return(nil)
+
+	client := pluginapi.NewClient(api, &plugintest.Driver{})
+	store := kvstore.NewKVStore(client)
+	plugin := &Plugin{client: client, kvstore: store}
+	plugin.commandClient = command.NewCommandHandler(client, store, "com.mattermost.plugin-starter-template", "bot-id", nil, command.Config{})
+	plugin.SetAPI(api)
+	plugin.router = plugin.initRouter()
+	require.NotNil(t, registered)
+
+	// The server resolves the fetch URLs against the URL of the plugin when registering commands.
+	pluginURL := &url.URL{Scheme: "http", Host: "localhost:8065", Path: "/plugins/com.mattermost.plugin-starter-template"}
+	require.NoError(t, registered.AutocompleteData.UpdateRelativeURLsForPluginCommands(pluginURL))
+	cancel := registered.AutocompleteData
+	for _, trigger := range []string{"schedule", "cancel"} {
+		index := slices.IndexFunc(cancel.SubCommands, func(data *model.AutocompleteData) bool { return data.Trigger == trigger })
+		require.NotEqual(t, -1, index, trigger)
+		cancel = cancel.SubCommands[index]
+	}
+	fetchURL, err := url.Parse(cancel.Arguments[0].Data.(*model.AutocompleteDynamicListArg).FetchURL)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, strings.TrimPrefix(fetchURL.EscapedPath(), pluginURL.Path)+"?user_input=%2Fhello+schedule+cancel+", nil)
+	r.Header.Set("Mattermost-User-ID", "test-user-id")
+	plugin.ServeHTTP(nil, w, r)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var items []model.AutocompleteListItem
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&items))
+	assert.Empty(t, items)
+}
+
+func TestSubmitDialog(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	commandClient := mocks.NewMockCommand(ctrl)
+
+	plugin := Plugin{commandClient: commandClient}
+	api := &plugintest.API{}
+	mockRequestLogging(api)
+	plugin.SetAPI(api)
+	plugin.router = plugin.initRouter()
+
+	submit := func(request *model.SubmitDialogRequest) *http.Response {
+		body, err := json.Marshal(request)
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/dialog", strings.NewReader(string(body)))
+		r.Header.Set("Mattermost-User-ID", "test-user-id")
+
+		plugin.ServeHTTP(nil, w, r)
+		return w.Result()
+	}
+
+	t.Run("uses the authenticated user", func(t *testing.T) {
+		request := command.DialogSubmitRequest("greeting", "state", "spoofed-user-id", map[string]any{"message": ""})
+		commandClient.EXPECT().
+			SubmitDialog(gomock.Cond(func(r *model.SubmitDialogRequest) bool { return r.UserId == "test-user-id" })).
+			Return(&model.SubmitDialogResponse{Errors: map[string]string{"message": "This field is required."}}, nil)
+
+		result := submit(request)
+		defer func() { _ = result.Body.Close() }()
+		require.Equal(t, http.StatusOK, result.StatusCode)
+
+		var response model.SubmitDialogResponse
+		require.NoError(t, json.NewDecoder(result.Body).Decode(&response))
+		assert.Equal(t, map[string]string{"message": "This field is required."}, response.Errors)
+	})
+
+	t.Run("empty response closes the dialog", func(t *testing.T) {
+		commandClient.EXPECT().SubmitDialog(gomock.Any()).Return(nil, nil)
+
+		result := submit(command.DialogSubmitRequest("greeting", "", "", nil))
+		defer func() { _ = result.Body.Close() }()
+		require.Equal(t, http.StatusOK, result.StatusCode)
+
+		body, err := io.ReadAll(result.Body)
+		require.NoError(t, err)
+		assert.Empty(t, body)
+	})
+}
+
+func TestHandleAction(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	commandClient := mocks.NewMockCommand(ctrl)
+
+	plugin := Plugin{commandClient: commandClient}
+	api := &plugintest.API{}
+	mockRequestLogging(api)
+	plugin.SetAPI(api)
+	plugin.router = plugin.initRouter()
 
-	assert.Equal("Hello, world!", bodyString)
+	commandClient.EXPECT().HandleAction(gomock.Any()).Return(nil, command.ErrInvalidSignature)
+
+	api.On("LogWarn", "Rejected action request with an invalid signature", "user_id", "test-user-id", "post_id", "post-id", "request_id", mock.Anything).Return()
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/actions", strings.NewReader(`{"post_id":"post-id","context":{"signature":"forged"}}`))
+	r.Header.Set("Mattermost-User-ID", "test-user-id")
+
+	plugin.ServeHTTP(nil, w, r)
+
+	result := w.Result()
+	defer func() { _ = result.Body.Close() }()
+	assert.Equal(t, http.StatusForbidden, result.StatusCode)
+	api.AssertExpectations(t)
 }
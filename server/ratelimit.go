@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/mattermost/mattermost-plugin-starter-template/server/ratelimit"
+)
+
+// The route groups rate limits are configured for, see configuration.RateLimits.
+const (
+	rateLimitGroupAPI    = "api"
+	rateLimitGroupPlugin = "plugin"
+)
+
+// RateLimit limits the rate of the requests to the routes of the group, for every user, calling
+// plugin or, for anonymous requests, IP address. Requests exceeding the limit are rejected with
+// 429 Too Many Requests and a Retry-After header.
+func (p *Plugin) RateLimit(group string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if allowed, retryAfter := p.rateLimiter.Allow(rateLimitKey(r), group); !allowed {
+				seconds := ratelimit.RetryAfterSeconds(retryAfter)
+				w.Header().Set("Retry-After", strconv.Itoa(seconds))
+				p.writeError(w, r, model.NewAppError("RateLimit", "api.error.too_many_requests", map[string]any{"Seconds": seconds}, "", http.StatusTooManyRequests))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitKey returns the key of the bucket limiting the rate of the request: the ID of the
+// user or of the calling plugin, or else the IP address the request comes from.
+func rateLimitKey(r *http.Request) string {
+	if userID := r.Header.Get("Mattermost-User-ID"); userID != "" {
+		return userID
+	}
+	if pluginID := r.Header.Get("Mattermost-Plugin-ID"); pluginID != "" {
+		return "plugin_" + pluginID
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip_" + host
+}
@@ -0,0 +1,192 @@
+// Package ratelimit limits the rate of the requests of every user with token buckets shared by
+// the nodes of the cluster through the KV store.
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-starter-template/server/store/kvstore"
+)
+
+// Limit allows a number of requests per period, in bursts of up to that many requests.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// perSecond returns the rate at which the bucket of the limit refills.
+func (l Limit) perSecond() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// ParseLimits parses a comma separated list of limits by name, e.g.
+// `api: 120/m, /hello: 20/m, /hello schedule: 5/10m`. A limit is a number of requests per
+// second (`s`), minute (`m`), hour (`h`) or duration, e.g. `10m`.
+func ParseLimits(s string) (map[string]Limit, error) {
+	limits := map[string]Limit{}
+	for _, entry := range strings.Split(s, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(entry, ":")
+		name = strings.Join(strings.Fields(strings.ToLower(name)), " ")
+		if !ok || name == "" {
+			return nil, errors.Errorf("invalid rate limit %q, expected `name: requests/period`", strings.TrimSpace(entry))
+		}
+
+		limit, err := parseLimit(strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid rate limit of %s", name)
+		}
+		limits[name] = limit
+	}
+	return limits, nil
+}
+
+// parseLimit parses a limit, e.g. `20/m` or `5/10m`.
+func parseLimit(s string) (Limit, error) {
+	requests, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, errors.Errorf("%q is not of the form requests/period", s)
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(requests))
+	if err != nil || count <= 0 {
+		return Limit{}, errors.Errorf("%q is not a positive number of requests", requests)
+	}
+
+	period = strings.TrimSpace(period)
+	if period == "s" || period == "m" || period == "h" {
+		period = "1" + period
+	}
+	duration, err := time.ParseDuration(period)
+	if err != nil || duration < time.Second {
+		return Limit{}, errors.Errorf("%q is not a period of at least a second", period)
+	}
+
+	return Limit{Requests: count, Period: duration}, nil
+}
+
+// Limiter limits the rate of the requests of every user, by name, e.g. the route group or
+// command they are for. The buckets are shared by the cluster nodes, and the limits may be
+// exceeded slightly when nodes update a bucket concurrently or their clocks drift.
+type Limiter struct {
+	store kvstore.KVStore
+	log   pluginapi.LogService
+
+	// now returns the current time, replaced in tests.
+	now func() time.Time
+
+	// limitsLock synchronizes access to limits.
+	limitsLock sync.RWMutex
+
+	// limits are the configured limits, by name.
+	limits map[string]Limit
+}
+
+// NewLimiter returns a limiter storing its buckets in the store, without limits until it is
+// configured.
+func NewLimiter(store kvstore.KVStore, log pluginapi.LogService) *Limiter {
+	return &Limiter{
+		store: store,
+		log:   log,
+		now:   time.Now,
+	}
+}
+
+// Configure replaces the limits, by name. It is safe to call while requests are being limited,
+// typically from OnConfigurationChange.
+func (l *Limiter) Configure(limits map[string]Limit) {
+	l.limitsLock.Lock()
+	defer l.limitsLock.Unlock()
+
+	l.limits = limits
+}
+
+// limit returns the limit of the first of the names having one.
+func (l *Limiter) limit(names []string) (string, Limit, bool) {
+	l.limitsLock.RLock()
+	defer l.limitsLock.RUnlock()
+
+	for _, name := range names {
+		if limit, ok := l.limits[name]; ok {
+			return name, limit, true
+		}
+	}
+	return "", Limit{}, false
+}
+
+// Allow takes a token from the bucket of the user for the first of the names having a limit,
+// e.g. the most specific command. If none is left, it returns false and how long to wait for the
+// next one. Requests are allowed if none of the names has a limit, if the limiter is nil, or if
+// the bucket cannot be updated, so that an unavailable KV store does not lock users out.
+func (l *Limiter) Allow(userID string, names ...string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	name, limit, ok := l.limit(names)
+	if !ok {
+		return true, 0
+	}
+
+	var allowed bool
+	var retryAfter time.Duration
+	err := l.store.UpdateRateLimitBucket(bucketKey(userID, name), bucketExpiry(limit), func(bucket *kvstore.RateLimitBucket) {
+		allowed, retryAfter = take(bucket, limit, l.now())
+	})
+	if err != nil {
+		l.log.Warn("Failed to apply rate limit, allowing the request", "user_id", userID, "limit", name, "error", err)
+		return true, 0
+	}
+
+	return allowed, retryAfter
+}
+
+// take refills the bucket for the time elapsed since it was last updated, then takes a token
+// from it if one is left. Otherwise, it returns how long to wait for the next token.
+func take(bucket *kvstore.RateLimitBucket, limit Limit, now time.Time) (bool, time.Duration) {
+	capacity := float64(limit.Requests)
+
+	if bucket.UpdatedAt == 0 {
+		bucket.Tokens = capacity
+	} else if elapsed := now.Sub(time.UnixMilli(bucket.UpdatedAt)); elapsed > 0 {
+		bucket.Tokens = math.Min(capacity, bucket.Tokens+elapsed.Seconds()*limit.perSecond())
+	}
+	bucket.UpdatedAt = now.UnixMilli()
+
+	if bucket.Tokens >= 1 {
+		bucket.Tokens--
+		return true, 0
+	}
+
+	wait := (1 - bucket.Tokens) / limit.perSecond()
+	return false, time.Duration(math.Ceil(wait * float64(time.Second)))
+}
+
+// bucketKey returns the key of the bucket of the user for the named limit. The name is hashed,
+// as it may be longer than a key allows.
+func bucketKey(userID, name string) string {
+	hash := sha256.Sum256([]byte(name))
+	return userID + "-" + hex.EncodeToString(hash[:8])
+}
+
+// bucketExpiry returns how long a bucket is kept once updated: the time it takes to refill
+// entirely, after which it is as good as new.
+func bucketExpiry(limit Limit) time.Duration {
+	return max(limit.Period, time.Second)
+}
+
+// RetryAfterSeconds rounds up how long to wait to whole seconds, as in a Retry-After header.
+func RetryAfterSeconds(retryAfter time.Duration) int {
+	return max(1, int(math.Ceil(retryAfter.Seconds())))
+}
@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-starter-template/server/store/kvstore"
+)
+
+func TestParseLimits(t *testing.T) {
+	limits, err := ParseLimits(" api: 120/m, /Hello  Schedule: 5/10m,, plugin:1/s ")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Limit{
+		"api":             {Requests: 120, Period: time.Minute},
+		"/hello schedule": {Requests: 5, Period: 10 * time.Minute},
+		"plugin":          {Requests: 1, Period: time.Second},
+	}, limits)
+
+	limits, err = ParseLimits("")
+	require.NoError(t, err)
+	assert.Empty(t, limits)
+
+	for _, invalid := range []string{"api", "api: 120", ": 1/m", "api: 0/m", "api: x/m", "api: 1/d", "api: 1/10ms"} {
+		_, err := ParseLimits(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestTake(t *testing.T) {
+	limit := Limit{Requests: 2, Period: time.Minute}
+	now := time.UnixMilli(1_700_000_000_000)
+	bucket := &kvstore.RateLimitBucket{}
+
+	for range 2 {
+		allowed, _ := take(bucket, limit, now)
+		assert.True(t, allowed)
+	}
+	allowed, retryAfter := take(bucket, limit, now)
+	assert.False(t, allowed)
+	assert.InDelta(t, 30*time.Second, retryAfter, float64(time.Millisecond))
+
+	allowed, retryAfter = take(bucket, limit, now.Add(20*time.Second))
+	assert.False(t, allowed)
+	assert.InDelta(t, 10*time.Second, retryAfter, float64(time.Millisecond))
+
+	allowed, _ = take(bucket, limit, now.Add(30*time.Second))
+	assert.True(t, allowed)
+
+	// The bucket never holds more than its capacity.
+	take(bucket, limit, now.Add(time.Hour))
+	assert.InDelta(t, 1, bucket.Tokens, 0.001)
+}
+
+func TestLimiter(t *testing.T) {
+	api := &plugintest.API{}
+	client := pluginapi.NewClient(api, &plugintest.Driver{})
+	limiter := NewLimiter(kvstore.NewKVStore(client), client.Log)
+	now := time.UnixMilli(1_700_000_000_000)
+	limiter.now = func() time.Time { return now }
+	limiter.Configure(map[string]Limit{
+		"/hello":          {Requests: 10, Period: time.Minute},
+		"/hello schedule": {Requests: 1, Period: time.Hour},
+	})
+
+	key := "ratelimit-" + bucketKey("user-id", "/hello schedule")
+	var stored []byte
+	api.On("KVGet", key).Return(func(string) []byte { return stored }, nil)
+	api.On("KVSetWithOptions", key, mock.Anything, mock.Anything).Return(func(_ string, value []byte, options model.PluginKVSetOptions) bool {
+		assert.True(t, options.Atomic)
+		assert.Equal(t, stored, options.OldValue)
+		assert.Equal(t, int64(3600), options.ExpireInSeconds)
+		stored = value
+		return true
+	}, nil)
+
+	t.Run("the most specific limit applies", func(t *testing.T) {
+		allowed, _ := limiter.Allow("user-id", "/hello schedule in", "/hello schedule", "/hello")
+		assert.True(t, allowed)
+
+		var bucket kvstore.RateLimitBucket
+		require.NoError(t, json.Unmarshal(stored, &bucket))
+		assert.Equal(t, kvstore.RateLimitBucket{Tokens: 0, UpdatedAt: now.UnixMilli()}, bucket)
+
+		allowed, retryAfter := limiter.Allow("user-id", "/hello schedule in", "/hello schedule", "/hello")
+		assert.False(t, allowed)
+		assert.InDelta(t, time.Hour, retryAfter, float64(time.Millisecond))
+	})
+
+	t.Run("requests without a limit are allowed", func(t *testing.T) {
+		allowed, _ := limiter.Allow("user-id", "/other")
+		assert.True(t, allowed)
+		api.AssertNumberOfCalls(t, "KVSetWithOptions", 2)
+	})
+
+	t.Run("requests are allowed if the store fails", func(t *testing.T) {
+		failing := "ratelimit-" + bucketKey("user-id", "/hello")
+		api.On("KVGet", failing).Return(nil, model.NewAppError("KVGet", "app.plugin_store.get.app_error", nil, "", 500))
+		api.On("LogWarn", "Failed to apply rate limit, allowing the request", "user_id", "user-id", "limit", "/hello", "error", mock.Anything).Return().Once()
+
+		allowed, _ := limiter.Allow("user-id", "/hello")
+		assert.True(t, allowed)
+		api.AssertExpectations(t)
+	})
+
+	t.Run("a nil limiter allows everything", func(t *testing.T) {
+		var limiter *Limiter
+		allowed, _ := limiter.Allow("user-id", "/hello")
+		assert.True(t, allowed)
+	})
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-starter-template/server/ratelimit"
+	"github.com/mattermost/mattermost-plugin-starter-template/server/store/kvstore"
+)
+
+func TestRateLimit(t *testing.T) {
+	api := &plugintest.API{}
+	mockKVStore(api)
+	mockRequestLogging(api)
+
+	client := pluginapi.NewClient(api, &plugintest.Driver{})
+	plugin := &Plugin{client: client}
+	plugin.SetAPI(api)
+	plugin.rateLimiter = ratelimit.NewLimiter(kvstore.NewKVStore(client), client.Log)
+	plugin.rateLimiter.Configure(map[string]ratelimit.Limit{rateLimitGroupAPI: {Requests: 1, Period: time.Minute}})
+	plugin.router = plugin.initRouter()
+
+	serve := func(userID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/hello", nil)
+		r.Header.Set("Mattermost-User-ID", userID)
+		plugin.ServeHTTP(nil, w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, serve("user-id").Code)
+
+	w := serve("user-id")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	var appErr model.AppError
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&appErr))
+	assert.Equal(t, "api.error.too_many_requests", appErr.Id)
+
+	assert.Equal(t, http.StatusOK, serve("other-user-id").Code, "every user has their own bucket")
+}
+
+func TestRateLimitKey(t *testing.T) {
+	for name, tc := range map[string]struct {
+		userID     string
+		pluginID   string
+		remoteAddr string
+		expected   string
+	}{
+		"user":         {userID: "user-id", pluginID: "com.example.plugin", remoteAddr: "192.0.2.1:1234", expected: "user-id"},
+		"plugin":       {pluginID: "com.example.plugin", remoteAddr: "192.0.2.1:1234", expected: "plugin_com.example.plugin"},
+		"anonymous":    {remoteAddr: "192.0.2.1:1234", expected: "ip_192.0.2.1"},
+		"IPv6":         {remoteAddr: "[2001:db8::1]:1234", expected: "ip_2001:db8::1"},
+		"without port": {remoteAddr: "192.0.2.1", expected: "ip_192.0.2.1"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/webhooks/id", nil)
+			r.RemoteAddr = tc.remoteAddr
+			if tc.userID != "" {
+				r.Header.Set("Mattermost-User-ID", tc.userID)
+			}
+			if tc.pluginID != "" {
+				r.Header.Set("Mattermost-Plugin-ID", tc.pluginID)
+			}
+			assert.Equal(t, tc.expected, rateLimitKey(r))
+		})
+	}
+}
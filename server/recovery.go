@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync/atomic"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// panicCounter counts the panics recovered from by the API handlers.
+type panicCounter struct {
+	count       atomic.Int64
+	lastPanicAt atomic.Int64
+}
+
+// record counts a panic recovered from at the given time, in milliseconds since the epoch.
+func (c *panicCounter) record(at int64) {
+	c.count.Add(1)
+	c.lastPanicAt.Store(at)
+	httpPanics.Inc()
+}
+
+// Recovery recovers from the panics of the handlers, so that they do not reach the RPC layer of
+// the server. The panic is logged with its stack trace and the ID of the request, which the
+// client receives in a JSON error with status 500, and is counted in the health and metrics of
+// the plugin.
+func (p *Plugin) Recovery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				// Deliberately aborted responses are not failures of the handler.
+				panic(recovered)
+			}
+
+			p.panics.record(model.GetMillis())
+			p.requestLogger(r).Error("Recovered from a panic in an API handler",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"panic", fmt.Sprint(recovered),
+				"stack", string(debug.Stack()),
+			)
+
+			// The response cannot be replaced once its status was sent.
+			if recorder.wroteHeader {
+				return
+			}
+			p.writeError(recorder, r, model.NewAppError("Recovery", "api.error.internal", nil, "", http.StatusInternalServerError))
+		}()
+
+		next.ServeHTTP(recorder, r)
+	})
+}
+
+// HealthResponse is the response of the health endpoint.
+type HealthResponse struct {
+	// Panics is how many panics the API handlers recovered from since the plugin started.
+	Panics int64 `json:"panics"`
+
+	// LastPanicAt is when the last panic was recovered from, in milliseconds since the epoch,
+	// or 0 if none was.
+	LastPanicAt int64 `json:"last_panic_at"`
+}
+
+// Health reports the health of the plugin.
+func (p *Plugin) Health(r *Request, _ *Empty) (*HealthResponse, error) {
+	return &HealthResponse{
+		Panics:      p.panics.count.Load(),
+		LastPanicAt: p.panics.lastPanicAt.Load(),
+	}, nil
+}
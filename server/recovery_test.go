@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecovery(t *testing.T) {
+	api := &plugintest.API{}
+	mockRequestLogging(api)
+	api.On("LogError", "Recovered from a panic in an API handler",
+		"method", http.MethodGet,
+		"path", mock.AnythingOfType("string"),
+		"panic", "boom",
+		"stack", mock.MatchedBy(func(stack string) bool { return strings.Contains(stack, "TestRecovery") }),
+		"request_id", "request-id",
+	).Return()
+	plugin := &Plugin{}
+	plugin.SetAPI(api)
+
+	router := mux.NewRouter()
+	router.Use(plugin.RequestLogging, plugin.Recovery)
+	router.HandleFunc("/panic", func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	})
+	router.HandleFunc("/panic-after-write", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("boom")
+	})
+	router.HandleFunc("/abort", func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set(requestIDHeader, "request-id")
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("panics are reported as internal errors", func(t *testing.T) {
+		w := serve("/panic")
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		var appErr model.AppError
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&appErr))
+		assert.Equal(t, "api.error.internal", appErr.Id)
+		assert.Equal(t, "request-id", appErr.RequestId)
+		assert.Equal(t, int64(1), plugin.panics.count.Load())
+		assert.NotZero(t, plugin.panics.lastPanicAt.Load())
+	})
+
+	t.Run("responses already started are kept", func(t *testing.T) {
+		w := serve("/panic-after-write")
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Empty(t, w.Body.String())
+		assert.Equal(t, int64(2), plugin.panics.count.Load())
+	})
+
+	t.Run("aborted responses are not recovered from", func(t *testing.T) {
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() { serve("/abort") })
+		assert.Equal(t, int64(2), plugin.panics.count.Load())
+	})
+}
+
+func TestHealth(t *testing.T) {
+	api := &plugintest.API{}
+	mockRequestLogging(api)
+	api.On("HasPermissionTo", "admin-id", model.PermissionManageSystem).Return(true)
+	api.On("HasPermissionTo", "user-id", model.PermissionManageSystem).Return(false)
+	api.On("LogWarn", "Request not permitted", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	plugin := &Plugin{client: pluginapi.NewClient(api, &plugintest.Driver{})}
+	plugin.SetAPI(api)
+	plugin.router = plugin.initRouter()
+	plugin.panics.record(1_700_000_000_000)
+
+	serve := func(userID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/health", nil)
+		r.Header.Set("Mattermost-User-ID", userID)
+		plugin.ServeHTTP(nil, w, r)
+		return w
+	}
+
+	w := serve("admin-id")
+	require.Equal(t, http.StatusOK, w.Code)
+	var health HealthResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&health))
+	assert.Equal(t, HealthResponse{Panics: 1, LastPanicAt: 1_700_000_000_000}, health)
+
+	assert.Equal(t, http.StatusForbidden, serve("user-id").Code)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// publicDir holds the assets served by the plugin, relative to the plugin bundle.
+	publicDir = "public"
+
+	// assetTemplateSuffix is the suffix of the assets rendered as HTML templates, e.g.
+	// `hello.html.tmpl` is served as `hello.html`.
+	assetTemplateSuffix = ".tmpl"
+
+	// assetMaxAge is how long browsers may cache the static assets, in seconds. They revalidate
+	// them with their ETag afterwards.
+	assetMaxAge = 60 * 60
+)
+
+// assetTemplateData is the data the asset templates are executed with.
+type assetTemplateData struct {
+	// PluginID is the ID of the plugin, e.g. to build URLs pointing back to the plugin.
+	PluginID string
+
+	// User is the current user, without their secrets, or nil for anonymous requests.
+	User *model.User
+
+	// Config is the configuration of the plugin.
+	Config *configuration
+}
+
+// ServeAsset serves the files of the public/ directory of the plugin bundle. Their content type
+// is detected from their extension or content, and they are cached for an hour and revalidated
+// with their ETag. Files ending in `.tmpl`, e.g. `hello.html.tmpl`, are HTML templates served
+// without the suffix, executed with the current user and configuration, and never cached by
+// shared caches. Paths leaving the directory are rejected.
+func (p *Plugin) ServeAsset(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["path"]
+	if !filepath.IsLocal(name) || strings.HasSuffix(name, assetTemplateSuffix) {
+		p.writeError(w, r, model.NewAppError("ServeAsset", "api.error.asset_not_found", nil, "", http.StatusNotFound))
+		return
+	}
+
+	bundlePath, err := p.client.System.GetBundlePath()
+	if err != nil {
+		p.writeError(w, r, errors.Wrap(err, "failed to get bundle path"))
+		return
+	}
+
+	// The root also rejects the symbolic links leaving the directory.
+	root, err := os.OpenRoot(filepath.Join(bundlePath, publicDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		p.writeError(w, r, model.NewAppError("ServeAsset", "api.error.asset_not_found", nil, "", http.StatusNotFound))
+		return
+	} else if err != nil {
+		p.writeError(w, r, errors.Wrap(err, "failed to open public directory"))
+		return
+	}
+	defer func() { _ = root.Close() }()
+
+	content, modTime, err := readAsset(root, name)
+	cacheControl := "public, max-age=" + strconv.Itoa(assetMaxAge)
+	if errors.Is(err, fs.ErrNotExist) {
+		content, err = p.renderAssetTemplate(r, root, name)
+		modTime = time.Time{}
+		cacheControl = "private, no-cache"
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		p.writeError(w, r, model.NewAppError("ServeAsset", "api.error.asset_not_found", nil, "", http.StatusNotFound))
+		return
+	} else if err != nil {
+		p.writeError(w, r, err)
+		return
+	}
+
+	sum := sha256.Sum256(content)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	// ServeContent detects the content type and answers conditional requests.
+	http.ServeContent(w, r, path.Base(name), modTime, bytes.NewReader(content))
+}
+
+// readAsset reads a regular file of the public directory, returning its content and when it was
+// modified. Files that cannot be opened, including through links leaving the directory, are
+// reported as fs.ErrNotExist.
+func readAsset(root *os.Root, name string) ([]byte, time.Time, error) {
+	file, err := root.Open(name)
+	if err != nil {
+		return nil, time.Time{}, fs.ErrNotExist
+	}
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, time.Time{}, errors.Wrapf(err, "failed to stat asset %s", name)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, time.Time{}, fs.ErrNotExist
+	}
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, time.Time{}, errors.Wrapf(err, "failed to read asset %s", name)
+	}
+	return content, info.ModTime(), nil
+}
+
+// renderAssetTemplate executes the template of the asset, with the current user and
+// configuration.
+func (p *Plugin) renderAssetTemplate(r *http.Request, root *os.Root, name string) ([]byte, error) {
+	text, _, err := readAsset(root, name+assetTemplateSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New(path.Base(name)).Parse(string(text))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse asset template %s", name)
+	}
+
+	manifest, err := p.client.System.GetManifest()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get plugin manifest")
+	}
+
+	data := &assetTemplateData{
+		PluginID: manifest.Id,
+		Config:   p.getConfiguration(),
+	}
+	if userID := r.Header.Get("Mattermost-User-ID"); userID != "" {
+		user, err := p.client.User.Get(userID)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get user")
+		}
+		user.Sanitize(map[string]bool{"email": true, "fullname": true})
+		data.User = user
+	}
+
+	var content bytes.Buffer
+	if err := tmpl.Execute(&content, data); err != nil {
+		return nil, errors.Wrapf(err, "failed to execute asset template %s", name)
+	}
+	return content.Bytes(), nil
+}
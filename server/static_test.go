@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeAsset(t *testing.T) {
+	bundlePath := t.TempDir()
+	public := filepath.Join(bundlePath, publicDir)
+	require.NoError(t, os.MkdirAll(filepath.Join(public, "css"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(bundlePath, "plugin.json"), []byte(`{"id":"com.example.plugin"}`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(bundlePath, "secret.txt"), []byte("secret"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(public, "css", "style.css"), []byte("body { margin: 0; }"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(public, "hello.html.tmpl"), []byte(`<p>Hello {{with .User}}{{.Username}}{{else}}stranger{{end}} from {{.PluginID}} with /{{.Config.HelloTrigger}}</p>`), 0o600))
+	require.NoError(t, os.Symlink(filepath.Join(bundlePath, "secret.txt"), filepath.Join(public, "secret.txt")))
+
+	api := &plugintest.API{}
+	api.On("GetBundlePath").Return(bundlePath, nil)
+	api.On("GetUser", "user-id").Return(&model.User{Id: "user-id", Username: "<alice>", Password: "hash"}, nil)
+	mockRequestLogging(api)
+
+	plugin := &Plugin{client: pluginapi.NewClient(api, &plugintest.Driver{})}
+	plugin.SetAPI(api)
+	plugin.setConfiguration(&configuration{HelloTrigger: "greet"})
+	plugin.router = plugin.initRouter()
+
+	serve := func(path string, header http.Header) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		for name, values := range header {
+			r.Header[name] = values
+		}
+		plugin.ServeHTTP(nil, w, r)
+		return w
+	}
+
+	t.Run("static files are cached and revalidated", func(t *testing.T) {
+		w := serve("/assets/css/style.css", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "body { margin: 0; }", w.Body.String())
+		assert.Equal(t, "text/css; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, "public, max-age=3600", w.Header().Get("Cache-Control"))
+		etag := w.Header().Get("ETag")
+		require.NotEmpty(t, etag)
+
+		w = serve("/assets/css/style.css", http.Header{"If-None-Match": {etag}})
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Empty(t, w.Body.String())
+	})
+
+	t.Run("templates are rendered for the current user", func(t *testing.T) {
+		w := serve("/assets/hello.html", http.Header{"Mattermost-User-Id": {"user-id"}})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "<p>Hello &lt;alice&gt; from com.example.plugin with /greet</p>", w.Body.String())
+		assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, "private, no-cache", w.Header().Get("Cache-Control"))
+
+		w = serve("/assets/hello.html", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "<p>Hello stranger from com.example.plugin with /greet</p>", w.Body.String())
+	})
+
+	for _, path := range []string{
+		"/assets/missing.css",
+		"/assets/hello.html.tmpl",
+		"/assets/css",
+		"/assets/secret.txt",
+	} {
+		t.Run(path+" is not found", func(t *testing.T) {
+			assert.Equal(t, http.StatusNotFound, serve(path, nil).Code)
+		})
+	}
+
+	t.Run("paths cannot leave the public directory", func(t *testing.T) {
+		// The router redirects to the cleaned paths, and the handler rejects the others.
+		for _, path := range []string{"/assets/../secret.txt", "/assets/css/..%2F..%2Fsecret.txt"} {
+			w := serve(path, nil)
+			assert.NotEqual(t, http.StatusOK, w.Code, path)
+			assert.NotContains(t, w.Body.String(), "secret", path)
+		}
+
+		for _, name := range []string{"../secret.txt", "/etc/passwd", "css/../../secret.txt"} {
+			w := httptest.NewRecorder()
+			plugin.ServeAsset(w, mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/assets/x", nil), map[string]string{"path": name}))
+			assert.Equal(t, http.StatusNotFound, w.Code, name)
+		}
+	})
+}
@@ -1,6 +1,69 @@
 package kvstore
 
+import "time"
+
+// ScheduledCommand is a slash command invocation scheduled to run later on behalf of a user.
+type ScheduledCommand struct {
+	ID        string `json:"id"`
+	UserID    string `json:"user_id"`
+	TeamID    string `json:"team_id"`
+	ChannelID string `json:"channel_id"`
+	RootID    string `json:"root_id"`
+
+	// Path holds the triggers of the command as declared, e.g. `hello alias add`, rather than as
+	// configured, so that the command still runs once its trigger is changed.
+	Path []string `json:"path"`
+
+	// Arguments are the quoted words following the path.
+	Arguments string `json:"arguments"`
+
+	// RunAt is when the command runs, in milliseconds since the epoch.
+	RunAt int64 `json:"run_at"`
+}
+
+// RateLimitBucket is the state of a token bucket limiting the rate of the requests of a user.
+type RateLimitBucket struct {
+	// Tokens are the requests the user may still make.
+	Tokens float64 `json:"tokens"`
+
+	// UpdatedAt is when the tokens were last counted, in milliseconds since the epoch, or 0 for a
+	// new bucket.
+	UpdatedAt int64 `json:"updated_at"`
+}
+
+// Webhook is an incoming webhook posting the payloads it receives to a channel.
+type Webhook struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channel_id"`
+
+	// Template is the Go template of the posts, executed with the JSON payload.
+	Template string `json:"template"`
+
+	// Secret signs the payloads of the webhook.
+	Secret string `json:"secret"`
+
+	CreatorID string `json:"creator_id"`
+	CreateAt  int64  `json:"create_at"`
+}
+
 type KVStore interface {
 	// Define your methods here. This package is used to access the KVStore pluginapi methods.
 	GetTemplateData(userID string) (string, error)
+	SetTemplateData(userID, templateData string) error
+	DeleteTemplateData(userID string) error
+	GetUserAliases(userID string) (map[string]string, error)
+	SetUserAlias(userID, name, expansion string) error
+	GetTeamAliases(teamID string) (map[string]string, error)
+	SetTeamAlias(teamID, name, expansion string) error
+	GetActionSigningKey() ([]byte, error)
+	SaveScheduledCommand(command *ScheduledCommand) error
+	ListScheduledCommands(userID string) ([]*ScheduledCommand, error)
+	ListDueScheduledCommands(now time.Time) ([]*ScheduledCommand, error)
+	DeleteScheduledCommand(command *ScheduledCommand) (bool, error)
+	UpdateRateLimitBucket(key string, expiry time.Duration, update func(bucket *RateLimitBucket)) error
+	SaveWebhook(webhook *Webhook) error
+	GetWebhook(id string) (*Webhook, error)
+	ListWebhooks() ([]*Webhook, error)
+	DeleteWebhook(id string) error
+	RecordWebhookSignature(webhookID, signature string, expiry time.Duration) (bool, error)
 }
@@ -0,0 +1,73 @@
+package kvstore
+
+import (
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+
+	"github.com/mattermost/mattermost-plugin-starter-template/server/metrics"
+)
+
+var (
+	kvOperations = metrics.Default.NewCounterVec("kv_operations_total", "KV store operations, by operation and result.", "operation", "result")
+	kvDurations  = metrics.Default.NewHistogramVec("kv_operation_duration_seconds", "Duration of the KV store operations, in seconds.", metrics.DefaultBuckets, "operation")
+)
+
+// kvService is the part of pluginapi.KVService the store uses.
+type kvService interface {
+	Get(key string, o any) error
+	Set(key string, value any, options ...pluginapi.KVSetOption) (bool, error)
+	SetAtomicWithRetries(key string, valueFunc func(oldValue []byte) (newValue any, err error)) error
+	Delete(key string) error
+	ListKeys(page, count int, options ...pluginapi.ListKeysOption) ([]string, error)
+}
+
+// instrumentedKV counts the operations of the KV store and their durations.
+type instrumentedKV struct {
+	kv kvService
+}
+
+// observe records an operation that started at the given time.
+func observe(operation string, start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	kvOperations.Inc(operation, result)
+	kvDurations.Observe(time.Since(start).Seconds(), operation)
+}
+
+func (i instrumentedKV) Get(key string, o any) error {
+	start := time.Now()
+	err := i.kv.Get(key, o)
+	observe("get", start, err)
+	return err
+}
+
+func (i instrumentedKV) Set(key string, value any, options ...pluginapi.KVSetOption) (bool, error) {
+	start := time.Now()
+	saved, err := i.kv.Set(key, value, options...)
+	observe("set", start, err)
+	return saved, err
+}
+
+func (i instrumentedKV) SetAtomicWithRetries(key string, valueFunc func(oldValue []byte) (newValue any, err error)) error {
+	start := time.Now()
+	err := i.kv.SetAtomicWithRetries(key, valueFunc)
+	observe("set_atomic_with_retries", start, err)
+	return err
+}
+
+func (i instrumentedKV) Delete(key string) error {
+	start := time.Now()
+	err := i.kv.Delete(key)
+	observe("delete", start, err)
+	return err
+}
+
+func (i instrumentedKV) ListKeys(page, count int, options ...pluginapi.ListKeysOption) ([]string, error) {
+	start := time.Now()
+	keys, err := i.kv.ListKeys(page, count, options...)
+	observe("list_keys", start, err)
+	return keys, err
+}
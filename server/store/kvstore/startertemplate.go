@@ -1,29 +1,390 @@
 package kvstore
 
 import (
+	"crypto/rand"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/mattermost/mattermost/server/public/pluginapi"
 	"github.com/pkg/errors"
 )
 
+const (
+	templateKeyPrefix          = "template_key-"
+	userAliasesKeyPrefix       = "user_aliases-"
+	teamAliasesKeyPrefix       = "team_aliases-"
+	actionSigningKeyKey        = "action_signing_key"
+	scheduleKeyPrefix          = "schedule-"
+	scheduleUserIndexKeyPrefix = "schedule_user-"
+	scheduleDueIndexKey        = "schedule_due"
+	rateLimitKeyPrefix         = "ratelimit-"
+	webhookKeyPrefix           = "webhook-"
+	signatureKeyPrefix         = "webhook_signature-"
+)
+
 // We expose our calls to the KVStore pluginapi methods through this interface for testability and stability.
 // This allows us to better control which values are stored with which keys.
 
 type Client struct {
-	client *pluginapi.Client
+	store kvService
 }
 
 func NewKVStore(client *pluginapi.Client) KVStore {
 	return Client{
-		client: client,
+		store: instrumentedKV{kv: &client.KV},
 	}
 }
 
 // Sample method to get a key-value pair in the KV store
 func (kv Client) GetTemplateData(userID string) (string, error) {
 	var templateData string
-	err := kv.client.KV.Get("template_key-"+userID, &templateData)
+	err := kv.store.Get(templateKeyPrefix+userID, &templateData)
 	if err != nil {
 		return "", errors.Wrap(err, "failed to get template data")
 	}
 	return templateData, nil
 }
+
+// SetTemplateData stores the greeting template of the user.
+func (kv Client) SetTemplateData(userID, templateData string) error {
+	if _, err := kv.store.Set(templateKeyPrefix+userID, templateData); err != nil {
+		return errors.Wrap(err, "failed to set template data")
+	}
+	return nil
+}
+
+// DeleteTemplateData removes the greeting template of the user.
+func (kv Client) DeleteTemplateData(userID string) error {
+	if err := kv.store.Delete(templateKeyPrefix + userID); err != nil {
+		return errors.Wrap(err, "failed to delete template data")
+	}
+	return nil
+}
+
+// GetUserAliases returns the command aliases of the user, by name.
+func (kv Client) GetUserAliases(userID string) (map[string]string, error) {
+	return kv.getAliases(userAliasesKeyPrefix + userID)
+}
+
+// SetUserAlias adds or replaces a command alias of the user. An empty expansion removes it.
+func (kv Client) SetUserAlias(userID, name, expansion string) error {
+	return kv.setAlias(userAliasesKeyPrefix+userID, name, expansion)
+}
+
+// GetTeamAliases returns the command aliases shared by the members of the team, by name.
+func (kv Client) GetTeamAliases(teamID string) (map[string]string, error) {
+	return kv.getAliases(teamAliasesKeyPrefix + teamID)
+}
+
+// SetTeamAlias adds or replaces a command alias of the team. An empty expansion removes it.
+func (kv Client) SetTeamAlias(teamID, name, expansion string) error {
+	return kv.setAlias(teamAliasesKeyPrefix+teamID, name, expansion)
+}
+
+func (kv Client) getAliases(key string) (map[string]string, error) {
+	aliases := map[string]string{}
+	if err := kv.store.Get(key, &aliases); err != nil {
+		return nil, errors.Wrap(err, "failed to get aliases")
+	}
+	return aliases, nil
+}
+
+// setAlias updates the aliases stored under the key atomically, so that concurrent changes to
+// other aliases are not lost.
+func (kv Client) setAlias(key, name, expansion string) error {
+	err := kv.store.SetAtomicWithRetries(key, func(oldValue []byte) (any, error) {
+		aliases := map[string]string{}
+		if len(oldValue) > 0 {
+			if err := json.Unmarshal(oldValue, &aliases); err != nil {
+				return nil, errors.Wrap(err, "failed to unmarshal aliases")
+			}
+		}
+		if expansion == "" {
+			delete(aliases, name)
+		} else {
+			aliases[name] = expansion
+		}
+		return aliases, nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to set alias")
+	}
+	return nil
+}
+
+// GetActionSigningKey returns the key signing the context of interactive message actions. The key
+// is generated on first use, atomically so that all cluster nodes agree on it.
+func (kv Client) GetActionSigningKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, errors.Wrap(err, "failed to generate action signing key")
+	}
+	if _, err := kv.store.Set(actionSigningKeyKey, key, pluginapi.SetAtomic(nil)); err != nil {
+		return nil, errors.Wrap(err, "failed to set action signing key")
+	}
+
+	var stored []byte
+	if err := kv.store.Get(actionSigningKeyKey, &stored); err != nil {
+		return nil, errors.Wrap(err, "failed to get action signing key")
+	}
+	if len(stored) == 0 {
+		return nil, errors.New("action signing key not found")
+	}
+	return stored, nil
+}
+
+// listKeysPerPage is the number of keys fetched at a time when listing keys.
+const listKeysPerPage = 1000
+
+// listKeysWithPrefix lists the keys starting with the prefix. pluginapi filters the keys by
+// prefix after paging, so that a page may hold fewer matching keys even if more follow: the
+// pages are listed unfiltered instead, and only a short page marks the end of the keys.
+func (kv Client) listKeysWithPrefix(prefix string) ([]string, error) {
+	var keys []string
+	for page := 0; ; page++ {
+		pageKeys, err := kv.store.ListKeys(page, listKeysPerPage)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range pageKeys {
+			if strings.HasPrefix(key, prefix) {
+				keys = append(keys, key)
+			}
+		}
+
+		if len(pageKeys) < listKeysPerPage {
+			return keys, nil
+		}
+	}
+}
+
+// scheduleKey returns the key of a scheduled command.
+func scheduleKey(userID, id string) string {
+	return scheduleKeyPrefix + userID + "-" + id
+}
+
+// scheduleIndexEntry locates a scheduled command in the indexes of the scheduled commands, which
+// map their IDs to their entries.
+type scheduleIndexEntry struct {
+	UserID string `json:"user_id"`
+	RunAt  int64  `json:"run_at"`
+}
+
+// SaveScheduledCommand stores a scheduled command, and adds it to the index of the commands of
+// its user and to the index of the commands by when they run.
+func (kv Client) SaveScheduledCommand(command *ScheduledCommand) error {
+	if _, err := kv.store.Set(scheduleKey(command.UserID, command.ID), command); err != nil {
+		return errors.Wrap(err, "failed to save scheduled command")
+	}
+
+	entry := &scheduleIndexEntry{UserID: command.UserID, RunAt: command.RunAt}
+	if err := kv.updateScheduleIndex(scheduleUserIndexKeyPrefix+command.UserID, command.ID, entry); err != nil {
+		return err
+	}
+	return kv.updateScheduleIndex(scheduleDueIndexKey, command.ID, entry)
+}
+
+// ListScheduledCommands returns the scheduled commands of the user, ordered by when they run.
+func (kv Client) ListScheduledCommands(userID string) ([]*ScheduledCommand, error) {
+	return kv.listScheduledCommands(scheduleUserIndexKeyPrefix+userID, func(*scheduleIndexEntry) bool { return true })
+}
+
+// ListDueScheduledCommands returns the scheduled commands of all users that are due at the given
+// time, ordered by when they run.
+func (kv Client) ListDueScheduledCommands(now time.Time) ([]*ScheduledCommand, error) {
+	return kv.listScheduledCommands(scheduleDueIndexKey, func(entry *scheduleIndexEntry) bool {
+		return entry.RunAt <= now.UnixMilli()
+	})
+}
+
+// listScheduledCommands returns the scheduled commands of the index whose entries match, ordered
+// by when they run.
+func (kv Client) listScheduledCommands(indexKey string, match func(entry *scheduleIndexEntry) bool) ([]*ScheduledCommand, error) {
+	index := map[string]*scheduleIndexEntry{}
+	if err := kv.store.Get(indexKey, &index); err != nil {
+		return nil, errors.Wrap(err, "failed to get scheduled command index")
+	}
+
+	var commands []*ScheduledCommand
+	for id, entry := range index {
+		if !match(entry) {
+			continue
+		}
+
+		var command *ScheduledCommand
+		if err := kv.store.Get(scheduleKey(entry.UserID, id), &command); err != nil {
+			return nil, errors.Wrap(err, "failed to get scheduled command")
+		}
+		// The command may have run or been cancelled since the index was read.
+		if command != nil {
+			commands = append(commands, command)
+		}
+	}
+
+	sort.Slice(commands, func(i, j int) bool {
+		if commands[i].RunAt != commands[j].RunAt {
+			return commands[i].RunAt < commands[j].RunAt
+		}
+		return commands[i].ID < commands[j].ID
+	})
+
+	return commands, nil
+}
+
+// updateScheduleIndex sets the entry of a scheduled command in the index stored under the key,
+// atomically so that concurrent changes to other entries are not lost. A nil entry removes it.
+func (kv Client) updateScheduleIndex(indexKey, id string, entry *scheduleIndexEntry) error {
+	err := kv.store.SetAtomicWithRetries(indexKey, func(oldValue []byte) (any, error) {
+		index := map[string]*scheduleIndexEntry{}
+		if len(oldValue) > 0 {
+			if err := json.Unmarshal(oldValue, &index); err != nil {
+				return nil, errors.Wrap(err, "failed to unmarshal scheduled command index")
+			}
+		}
+		if entry == nil {
+			delete(index, id)
+		} else {
+			index[id] = entry
+		}
+		if len(index) == 0 {
+			return nil, nil
+		}
+		return index, nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to update scheduled command index")
+	}
+	return nil
+}
+
+// DeleteScheduledCommand deletes a scheduled command, atomically so that across the cluster
+// only one caller succeeds, and removes it from the indexes. It returns false if the command was
+// already deleted.
+func (kv Client) DeleteScheduledCommand(command *ScheduledCommand) (bool, error) {
+	key := scheduleKey(command.UserID, command.ID)
+
+	var stored []byte
+	if err := kv.store.Get(key, &stored); err != nil {
+		return false, errors.Wrap(err, "failed to get scheduled command")
+	}
+
+	deleted := false
+	if len(stored) > 0 {
+		var err error
+		deleted, err = kv.store.Set(key, nil, pluginapi.SetAtomic(stored))
+		if err != nil {
+			return false, errors.Wrap(err, "failed to delete scheduled command")
+		}
+	}
+
+	// The caller deleting the command removes it from the indexes, as does any caller finding it
+	// deleted, in case the previous one failed to.
+	if deleted || len(stored) == 0 {
+		if err := kv.updateScheduleIndex(scheduleUserIndexKeyPrefix+command.UserID, command.ID, nil); err != nil {
+			return false, err
+		}
+		if err := kv.updateScheduleIndex(scheduleDueIndexKey, command.ID, nil); err != nil {
+			return false, err
+		}
+	}
+	return deleted, nil
+}
+
+// rateLimitRetries is the number of attempts at updating a rate limiting bucket modified
+// concurrently, e.g. by another cluster node.
+const rateLimitRetries = 5
+
+// UpdateRateLimitBucket atomically updates the bucket stored under the key, which expires if it
+// is not updated again in time. The update may be called again if the bucket is modified
+// concurrently, and is given a zero bucket if none is stored.
+func (kv Client) UpdateRateLimitBucket(key string, expiry time.Duration, update func(bucket *RateLimitBucket)) error {
+	key = rateLimitKeyPrefix + key
+
+	for range rateLimitRetries {
+		var stored []byte
+		if err := kv.store.Get(key, &stored); err != nil {
+			return errors.Wrap(err, "failed to get rate limit bucket")
+		}
+
+		var bucket RateLimitBucket
+		if len(stored) > 0 {
+			if err := json.Unmarshal(stored, &bucket); err != nil {
+				return errors.Wrap(err, "failed to unmarshal rate limit bucket")
+			}
+		}
+		update(&bucket)
+
+		saved, err := kv.store.Set(key, bucket, pluginapi.SetAtomic(stored), pluginapi.SetExpiry(expiry))
+		if err != nil {
+			return errors.Wrap(err, "failed to set rate limit bucket")
+		}
+		if saved {
+			return nil
+		}
+	}
+
+	return errors.Errorf("failed to update rate limit bucket after %d attempts", rateLimitRetries)
+}
+
+// SaveWebhook stores an incoming webhook.
+func (kv Client) SaveWebhook(webhook *Webhook) error {
+	if _, err := kv.store.Set(webhookKeyPrefix+webhook.ID, webhook); err != nil {
+		return errors.Wrap(err, "failed to save webhook")
+	}
+	return nil
+}
+
+// GetWebhook returns the incoming webhook with the given ID, or nil if there is none.
+func (kv Client) GetWebhook(id string) (*Webhook, error) {
+	var webhook *Webhook
+	if err := kv.store.Get(webhookKeyPrefix+id, &webhook); err != nil {
+		return nil, errors.Wrap(err, "failed to get webhook")
+	}
+	return webhook, nil
+}
+
+// ListWebhooks returns the incoming webhooks, ordered by creation.
+func (kv Client) ListWebhooks() ([]*Webhook, error) {
+	keys, err := kv.listKeysWithPrefix(webhookKeyPrefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list webhooks")
+	}
+
+	var webhooks []*Webhook
+	for _, key := range keys {
+		var webhook *Webhook
+		if err := kv.store.Get(key, &webhook); err != nil {
+			return nil, errors.Wrap(err, "failed to get webhook")
+		}
+		// The webhook may have been deleted since the keys were listed.
+		if webhook != nil {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].CreateAt < webhooks[j].CreateAt })
+
+	return webhooks, nil
+}
+
+// DeleteWebhook deletes an incoming webhook.
+func (kv Client) DeleteWebhook(id string) error {
+	if err := kv.store.Delete(webhookKeyPrefix + id); err != nil {
+		return errors.Wrap(err, "failed to delete webhook")
+	}
+	return nil
+}
+
+// RecordWebhookSignature records the signature of a payload received by a webhook until it
+// expires, atomically so that across the cluster only the first delivery of a payload is
+// recorded. It returns false if the signature was already recorded.
+func (kv Client) RecordWebhookSignature(webhookID, signature string, expiry time.Duration) (bool, error) {
+	recorded, err := kv.store.Set(signatureKeyPrefix+webhookID+"-"+signature, true, pluginapi.SetAtomic(nil), pluginapi.SetExpiry(expiry))
+	if err != nil {
+		return false, errors.Wrap(err, "failed to record webhook signature")
+	}
+	return recorded, nil
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-starter-template/server/events"
+	"github.com/mattermost/mattermost-plugin-starter-template/server/store/kvstore"
+)
+
+const (
+	// webhookTimestampHeader carries when a payload was signed, in seconds since the epoch.
+	webhookTimestampHeader = "X-Webhook-Timestamp"
+
+	// webhookSignatureHeader carries the signature of a payload, `sha256=` followed by the hex
+	// encoded HMAC-SHA256 of the timestamp, a dot and the body, keyed with the webhook secret.
+	webhookSignatureHeader = "X-Webhook-Signature"
+
+	// webhookSignaturePrefix prefixes the hex encoded signatures.
+	webhookSignaturePrefix = "sha256="
+
+	// webhookTimestampTolerance is how far the timestamp of a payload may be from the current
+	// time. Older payloads are rejected, as are the repeated deliveries of a payload meanwhile.
+	webhookTimestampTolerance = 5 * time.Minute
+)
+
+// WebhookInfo describes an incoming webhook. The secret is only returned when the webhook is
+// created.
+type WebhookInfo struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channel_id"`
+	Template  string `json:"template"`
+	Secret    string `json:"secret,omitempty"`
+	CreatorID string `json:"creator_id"`
+	CreateAt  int64  `json:"create_at"`
+}
+
+// newWebhookInfo describes the webhook, without its secret.
+func newWebhookInfo(webhook *kvstore.Webhook) *WebhookInfo {
+	return &WebhookInfo{
+		ID:        webhook.ID,
+		ChannelID: webhook.ChannelID,
+		Template:  webhook.Template,
+		CreatorID: webhook.CreatorID,
+		CreateAt:  webhook.CreateAt,
+	}
+}
+
+// CreateWebhookRequest is the request creating an incoming webhook.
+type CreateWebhookRequest struct {
+	// ChannelID is the channel the webhook posts to.
+	ChannelID string `json:"channel_id"`
+
+	// Template is the Go template of the posts, executed with the JSON payload, e.g.
+	// `Build {{.build.number}} {{.status}}`.
+	Template string `json:"template"`
+}
+
+func (r *CreateWebhookRequest) IsValid() *model.AppError {
+	if !model.IsValidId(r.ChannelID) {
+		return model.NewAppError("CreateWebhookRequest.IsValid", "api.error.invalid_body", nil, "invalid channel_id", http.StatusBadRequest)
+	}
+	if _, err := parseWebhookTemplate(r.Template); err != nil {
+		return model.NewAppError("CreateWebhookRequest.IsValid", "api.error.invalid_webhook_template", nil, err.Error(), http.StatusBadRequest)
+	}
+	return nil
+}
+
+// parseWebhookTemplate parses the template of the posts of a webhook. As anyone knowing the secret
+// of a webhook gets its template executed, templates may only print fields of the payload, e.g.
+// `{{.build.number}}`: actions like range or if, pipelines and function calls are rejected.
+func parseWebhookTemplate(text string) (*template.Template, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, errors.New("the template is empty")
+	}
+	tmpl, err := template.New("webhook").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkWebhookTemplate(tmpl); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// checkWebhookTemplate checks that the template only contains text, comments and actions printing
+// the payload or one of its fields.
+func checkWebhookTemplate(tmpl *template.Template) error {
+	invalid := errors.New("templates may only contain text and fields of the payload")
+	if len(tmpl.Templates()) > 1 {
+		return invalid
+	}
+	if tmpl.Tree == nil {
+		return nil
+	}
+	for _, node := range tmpl.Tree.Root.Nodes {
+		switch node := node.(type) {
+		case *parse.TextNode, *parse.CommentNode:
+		case *parse.ActionNode:
+			if len(node.Pipe.Decl) > 0 || len(node.Pipe.Cmds) != 1 || len(node.Pipe.Cmds[0].Args) != 1 {
+				return invalid
+			}
+			switch node.Pipe.Cmds[0].Args[0].(type) {
+			case *parse.FieldNode, *parse.DotNode:
+			default:
+				return invalid
+			}
+		default:
+			return invalid
+		}
+	}
+	return nil
+}
+
+// CreateWebhook creates an incoming webhook posting to a channel, returning its secret.
+func (p *Plugin) CreateWebhook(r *Request, in *CreateWebhookRequest) (*WebhookInfo, error) {
+	if _, err := p.client.Channel.Get(in.ChannelID); errors.Is(err, pluginapi.ErrNotFound) {
+		return nil, model.NewAppError("CreateWebhook", "api.error.channel_not_found", nil, "", http.StatusBadRequest)
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to get channel")
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, errors.Wrap(err, "failed to generate webhook secret")
+	}
+
+	webhook := &kvstore.Webhook{
+		ID:        model.NewId(),
+		ChannelID: in.ChannelID,
+		Template:  in.Template,
+		Secret:    hex.EncodeToString(secret),
+		CreatorID: r.UserID,
+		CreateAt:  model.GetMillis(),
+	}
+	if err := p.kvstore.SaveWebhook(webhook); err != nil {
+		return nil, err
+	}
+
+	info := newWebhookInfo(webhook)
+	info.Secret = webhook.Secret
+	return info, nil
+}
+
+// ListWebhooks lists the incoming webhooks, without their secrets.
+func (p *Plugin) ListWebhooks(r *Request, _ *Empty) ([]*WebhookInfo, error) {
+	webhooks, err := p.kvstore.ListWebhooks()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]*WebhookInfo, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		infos = append(infos, newWebhookInfo(webhook))
+	}
+	return infos, nil
+}
+
+// DeleteWebhook deletes an incoming webhook.
+func (p *Plugin) DeleteWebhook(r *Request, _ *Empty) (Empty, error) {
+	webhook, err := p.kvstore.GetWebhook(r.Var("webhook_id"))
+	if err != nil {
+		return Empty{}, err
+	}
+	if webhook == nil {
+		return Empty{}, model.NewAppError("DeleteWebhook", "api.error.webhook_not_found", nil, "", http.StatusNotFound)
+	}
+
+	return Empty{}, p.kvstore.DeleteWebhook(webhook.ID)
+}
+
+// ReceiveWebhook posts the JSON payload of a signed request to the channel of the webhook, as
+// the bot. The request is not authenticated by the server, but by its signature.
+func (p *Plugin) ReceiveWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, err := p.kvstore.GetWebhook(mux.Vars(r)["webhook_id"])
+	if err != nil {
+		p.writeError(w, r, err)
+		return
+	}
+	if webhook == nil {
+		p.writeError(w, r, model.NewAppError("ReceiveWebhook", "api.error.webhook_not_found", nil, "", http.StatusNotFound))
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		p.writeError(w, r, model.NewAppError("ReceiveWebhook", "api.error.body_too_large", map[string]any{"Max": maxBytesErr.Limit}, "", http.StatusRequestEntityTooLarge))
+		return
+	} else if err != nil {
+		p.writeError(w, r, model.NewAppError("ReceiveWebhook", "api.error.invalid_body", nil, err.Error(), http.StatusBadRequest))
+		return
+	}
+
+	signature, reason := verifyWebhookSignature(webhook.Secret, r.Header, body, time.Now())
+	if reason != "" {
+		p.requestLogger(r).Warn("Rejected webhook payload", "webhook_id", webhook.ID, "reason", reason)
+		p.writeError(w, r, model.NewAppError("ReceiveWebhook", "api.error.invalid_webhook_signature", nil, "", http.StatusUnauthorized))
+		return
+	}
+
+	var payload any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err = decoder.Decode(&payload); err != nil {
+		p.writeError(w, r, model.NewAppError("ReceiveWebhook", "api.error.invalid_body", nil, err.Error(), http.StatusBadRequest))
+		return
+	}
+
+	// Only record valid payloads, so that forged requests cannot fill the KV store.
+	first, err := p.kvstore.RecordWebhookSignature(webhook.ID, signature, 2*webhookTimestampTolerance)
+	if err != nil {
+		p.writeError(w, r, err)
+		return
+	}
+	if !first {
+		p.requestLogger(r).Warn("Rejected webhook payload", "webhook_id", webhook.ID, "reason", "replayed")
+		p.writeError(w, r, model.NewAppError("ReceiveWebhook", "api.error.invalid_webhook_signature", nil, "", http.StatusUnauthorized))
+		return
+	}
+
+	message, err := renderWebhookPost(webhook.Template, payload)
+	if err != nil {
+		p.writeError(w, r, model.NewAppError("ReceiveWebhook", "api.error.invalid_webhook_payload", nil, err.Error(), http.StatusBadRequest))
+		return
+	}
+
+	post := &model.Post{
+		UserId:    p.botUserID,
+		ChannelId: webhook.ChannelID,
+		Message:   message,
+	}
+	if err := p.client.Post.CreatePost(post); err != nil {
+		p.writeError(w, r, errors.Wrap(err, "failed to create webhook post"))
+		return
+	}
+
+	events.WebhookPosted.Publish(&p.client.Frontend, events.WebhookPostedPayload{
+		WebhookID: webhook.ID,
+		ChannelID: webhook.ChannelID,
+		PostID:    post.Id,
+	}, events.ToChannel(webhook.ChannelID))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// verifyWebhookSignature verifies the signature of the body and that it was signed recently. It
+// returns the signature, or why it is rejected.
+func verifyWebhookSignature(secret string, header http.Header, body []byte, now time.Time) (string, string) {
+	timestamp := header.Get(webhookTimestampHeader)
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", "invalid timestamp"
+	}
+	if skew := now.Sub(time.Unix(seconds, 0)); skew > webhookTimestampTolerance || skew < -webhookTimestampTolerance {
+		return "", "expired timestamp"
+	}
+
+	signature, ok := strings.CutPrefix(header.Get(webhookSignatureHeader), webhookSignaturePrefix)
+	if !ok {
+		return "", "missing signature"
+	}
+	if !hmac.Equal([]byte(strings.ToLower(signature)), []byte(signWebhookPayload(secret, timestamp, body))) {
+		return "", "invalid signature"
+	}
+
+	return strings.ToLower(signature), ""
+}
+
+// signWebhookPayload returns the hex encoded signature of the body sent at the timestamp.
+func signWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// renderWebhookPost executes the template of a webhook with its payload.
+func renderWebhookPost(text string, payload any) (string, error) {
+	tmpl, err := parseWebhookTemplate(text)
+	if err != nil {
+		return "", err
+	}
+
+	var message strings.Builder
+	if err := tmpl.Execute(&message, payload); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(message.String()) == "" {
+		return "", errors.New("the post is empty")
+	}
+	return message.String(), nil
+}
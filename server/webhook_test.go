@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-starter-template/server/store/kvstore"
+)
+
+// mockKVStore backs the KV store of the API with a map, including atomic updates.
+func mockKVStore(api *plugintest.API) map[string][]byte {
+	stored := map[string][]byte{}
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte { return stored[key] }, nil)
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(func(key string, value []byte, options model.PluginKVSetOptions) bool {
+		if options.Atomic && !bytes.Equal(stored[key], options.OldValue) {
+			return false
+		}
+		if value == nil {
+			delete(stored, key)
+		} else {
+			stored[key] = value
+		}
+		return true
+	}, nil)
+	api.On("KVList", mock.AnythingOfType("int"), mock.AnythingOfType("int")).Return(func(page, perPage int) []string {
+		keys := slices.Sorted(func(yield func(string) bool) {
+			for key := range stored {
+				if !yield(key) {
+					return
+				}
+			}
+		})
+		start := min(page*perPage, len(keys))
+		return keys[start:min(start+perPage, len(keys))]
+	}, nil)
+	return stored
+}
+
+func TestWebhooks(t *testing.T) {
+	api := &plugintest.API{}
+	stored := mockKVStore(api)
+	mockRequestLogging(api)
+	api.On("HasPermissionTo", "admin-id", model.PermissionManageSystem).Return(true)
+	api.On("GetChannel", "channelid00000000000000000").Return(&model.Channel{Id: "channelid00000000000000000"}, nil)
+	api.On("LogWarn", "Rejected webhook payload", "webhook_id", mock.Anything, "reason", mock.Anything, "request_id", mock.Anything).Return()
+
+	client := pluginapi.NewClient(api, &plugintest.Driver{})
+	plugin := &Plugin{client: client, kvstore: kvstore.NewKVStore(client), botUserID: "bot-id"}
+	plugin.SetAPI(api)
+	plugin.router = plugin.initRouter()
+
+	serve := func(method, path string, header http.Header, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		for name, values := range header {
+			r.Header[name] = values
+		}
+		plugin.ServeHTTP(nil, w, r)
+		return w
+	}
+	admin := http.Header{"Mattermost-User-Id": {"admin-id"}}
+
+	w := serve(http.MethodPost, "/api/v1/webhooks", admin, `{"channel_id":"channelid00000000000000000","template":"Build {{.build.number}} {{.status}}"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var webhook WebhookInfo
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&webhook))
+	assert.Equal(t, "channelid00000000000000000", webhook.ChannelID)
+	assert.Equal(t, "admin-id", webhook.CreatorID)
+	require.Len(t, webhook.Secret, 64)
+
+	t.Run("invalid templates are rejected", func(t *testing.T) {
+		for name, text := range map[string]string{
+			"syntax error": `{{.status`,
+			"range":        `{{range 100000}}spam{{end}}`,
+			"if":           `{{if .status}}failed{{end}}`,
+			"define":       `{{define \"spam\"}}spam{{end}}{{template \"spam\"}}`,
+			"pipeline":     `{{.status | printf \"%q\"}}`,
+			"function":     `{{printf \"%0100000d\" 0}}`,
+			"variable":     `{{$status := .status}}`,
+		} {
+			w := serve(http.MethodPost, "/api/v1/webhooks", admin, `{"channel_id":"channelid00000000000000000","template":"`+text+`"}`)
+			assert.Equal(t, http.StatusBadRequest, w.Code, name)
+		}
+	})
+
+	t.Run("secrets are not listed", func(t *testing.T) {
+		w := serve(http.MethodGet, "/api/v1/webhooks", admin, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var webhooks []WebhookInfo
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&webhooks))
+		require.Len(t, webhooks, 1)
+		assert.Equal(t, webhook.ID, webhooks[0].ID)
+		assert.Empty(t, webhooks[0].Secret)
+	})
+
+	t.Run("webhooks beyond the first page of keys are listed", func(t *testing.T) {
+		// The keys of other data fill the first page, before the keys of the webhooks.
+		for i := range 1000 {
+			key := fmt.Sprintf("template_key-user-%04d", i)
+			stored[key] = []byte(`"template"`)
+			defer delete(stored, key)
+		}
+
+		w := serve(http.MethodGet, "/api/v1/webhooks", admin, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var webhooks []WebhookInfo
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&webhooks))
+		require.Len(t, webhooks, 1)
+		assert.Equal(t, webhook.ID, webhooks[0].ID)
+	})
+
+	body := `{"status":"passed","build":{"number":1234567}}`
+	signed := func(timestamp time.Time, body string) http.Header {
+		ts := strconv.FormatInt(timestamp.Unix(), 10)
+		return http.Header{
+			webhookTimestampHeader: {ts},
+			webhookSignatureHeader: {webhookSignaturePrefix + signWebhookPayload(webhook.Secret, ts, []byte(body))},
+		}
+	}
+	path := "/webhooks/" + webhook.ID
+
+	t.Run("signed payloads are posted by the bot", func(t *testing.T) {
+		api.On("CreatePost", &model.Post{UserId: "bot-id", ChannelId: "channelid00000000000000000", Message: "Build 1234567 passed"}).Return(&model.Post{Id: "post-id", ChannelId: "channelid00000000000000000"}, nil).Once()
+		api.On("PublishWebSocketEvent", "webhook_posted", map[string]any{"webhook_id": webhook.ID, "channel_id": "channelid00000000000000000", "post_id": "post-id"}, &model.WebsocketBroadcast{ChannelId: "channelid00000000000000000"}).Return().Once()
+
+		header := signed(time.Now(), body)
+		w := serve(http.MethodPost, path, header, body)
+		require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+		api.AssertNumberOfCalls(t, "CreatePost", 1)
+		api.AssertNumberOfCalls(t, "PublishWebSocketEvent", 1)
+
+		w = serve(http.MethodPost, path, header, body)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, "replayed payloads are rejected")
+	})
+
+	for name, header := range map[string]http.Header{
+		"unsigned":          {webhookTimestampHeader: {strconv.FormatInt(time.Now().Unix(), 10)}},
+		"expired":           signed(time.Now().Add(-10*time.Minute), body),
+		"signed for others": signed(time.Now(), `{"status":"failed"}`),
+	} {
+		t.Run(name+" payloads are rejected", func(t *testing.T) {
+			w := serve(http.MethodPost, path, header, body)
+			require.Equal(t, http.StatusUnauthorized, w.Code)
+			var appErr model.AppError
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&appErr))
+			assert.Equal(t, "api.error.invalid_webhook_signature", appErr.Id)
+		})
+	}
+
+	t.Run("deleted webhooks are not found", func(t *testing.T) {
+		w := serve(http.MethodDelete, "/api/v1"+path, admin, "")
+		require.Equal(t, http.StatusNoContent, w.Code)
+
+		w = serve(http.MethodPost, path, signed(time.Now(), body), body)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,24 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+import manifest from 'manifest';
+
+import type {WebSocketMessage} from '@mattermost/client';
+
+import type {PluginRegistry} from 'types/mattermost-webapp';
+import type {WebSocketEventName, WebSocketEvents} from 'websocket_events';
+
+// The server prefixes the names of the events published by plugins.
+export function webSocketEventType(event: WebSocketEventName) {
+    return `custom_${manifest.id}_${event}`;
+}
+
+// Registers a handler for an event published by the server, typed by its payload.
+// The events and their payloads are defined in server/events and generated by `make apply`.
+export function registerWebSocketEventHandler<E extends WebSocketEventName>(
+    registry: PluginRegistry,
+    event: E,
+    handler: (msg: WebSocketMessage<WebSocketEvents[E]>) => void,
+) {
+    registry.registerWebSocketEventHandler<WebSocketEvents[E]>(webSocketEventType(event), handler);
+}